@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"context-id-backend/internal/service"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+type AccountController struct{}
+
+var Account = &AccountController{}
+
+// Delete 删除当前用户的账号：Casdoor用户随即删除，本地个人信息在保留期后匿名化
+func (c *AccountController) Delete(r *ghttp.Request) {
+	ctx := r.Context()
+
+	// 个人访问令牌可能被用于自动化脚本，删除账号必须由用户本人登录后操作
+	identity := userIdentity(r, false, "服务令牌和个人访问令牌不能删除账号，请登录后操作")
+	if identity == nil {
+		return
+	}
+
+	if err := service.Account.Delete(ctx, identity.User); err != nil {
+		g.Log().Error(ctx, "Account deletion failed:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "删除账号失败，请稍后重试",
+		})
+		return
+	}
+
+	Auth.clearRefreshCookie(r)
+	Auth.clearSessionCookies(r)
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "账号已删除",
+	})
+}
@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context-id-backend/internal/model"
+	"context-id-backend/internal/service"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+type AdminUserController struct{}
+
+var AdminUser = &AdminUserController{}
+
+// List 分页查询本地用户，支持按状态、用户名、邮箱过滤，关键字搜索和排序
+func (c *AdminUserController) List(r *ghttp.Request) {
+	ctx := r.Context()
+
+	var req *model.UserListReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	users, total, err := service.UserAdmin.List(ctx, req)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to list users:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "获取用户列表失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"users":     users,
+			"total":     total,
+			"page":      req.Page,
+			"page_size": req.PageSize,
+		},
+	})
+}
+
+// Get 获取单个用户
+func (c *AdminUserController) Get(r *ghttp.Request) {
+	user, err := service.UserAdmin.Get(r.Context(), r.Get("id").Uint64())
+	c.writeUser(r, user, err, "获取用户失败")
+}
+
+// Update 修改用户资料（显示名称、邮箱、手机号、头像），同时写入Casdoor
+func (c *AdminUserController) Update(r *ghttp.Request) {
+	var req *model.UserUpdateReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := service.UserAdmin.Update(r.Context(), r.Get("id").Uint64(), req)
+	c.writeUser(r, user, err, "修改用户失败")
+}
+
+// Suspend 暂停用户，用户的所有令牌和会话随即失效
+func (c *AdminUserController) Suspend(r *ghttp.Request) {
+	var req *model.UserStatusReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := service.UserAdmin.Suspend(r.Context(), r.Get("id").Uint64(), req.Reason)
+	c.writeUser(r, user, err, "暂停用户失败")
+}
+
+// Reactivate 恢复被暂停的用户
+func (c *AdminUserController) Reactivate(r *ghttp.Request) {
+	var req *model.UserStatusReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := service.UserAdmin.Reactivate(r.Context(), r.Get("id").Uint64(), req.Reason)
+	c.writeUser(r, user, err, "恢复用户失败")
+}
+
+// StatusHistory 用户的状态变更记录（原因、来源、操作的管理员和时间）
+func (c *AdminUserController) StatusHistory(r *ghttp.Request) {
+	changes, err := service.UserAdmin.StatusHistory(r.Context(), r.Get("id").Uint64())
+	if errors.Is(err, service.ErrUserNotFound) {
+		r.Response.Status = 404
+		r.Response.WriteJson(g.Map{
+			"code":    404,
+			"message": "用户不存在",
+		})
+		return
+	}
+	if err != nil {
+		g.Log().Error(r.Context(), "获取状态变更记录失败:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "获取状态变更记录失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"history": changes,
+		},
+	})
+}
+
+// writeUser 返回操作后的用户，或将错误转换为对应的状态码
+func (c *AdminUserController) writeUser(r *ghttp.Request, user *model.User, err error, failure string) {
+	switch {
+	case err == nil:
+		r.Response.WriteJson(g.Map{
+			"code":    200,
+			"message": "success",
+			"data": g.Map{
+				"user": user,
+			},
+		})
+	case errors.Is(err, service.ErrUserNotFound):
+		r.Response.Status = 404
+		r.Response.WriteJson(g.Map{
+			"code":    404,
+			"message": "用户不存在",
+		})
+	case errors.Is(err, service.ErrUserEmailTaken):
+		r.Response.Status = 409
+		r.Response.WriteJson(g.Map{
+			"code":    409,
+			"message": "邮箱已被其他用户使用",
+			"error":   "email_taken",
+		})
+	case errors.Is(err, service.ErrUserStatusConflict):
+		r.Response.Status = 409
+		r.Response.WriteJson(g.Map{
+			"code":    409,
+			"message": "用户当前状态不允许该操作",
+			"error":   "invalid_status",
+		})
+	default:
+		g.Log().Error(r.Context(), failure+":", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": failure,
+		})
+	}
+}
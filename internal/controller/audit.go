@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context-id-backend/internal/model"
+	"context-id-backend/internal/service"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+type AuditController struct{}
+
+var Audit = &AuditController{}
+
+// List 查询所有审计事件（管理员），支持按事件类型、用户、结果和时间范围过滤
+func (c *AuditController) List(r *ghttp.Request) {
+	var req *model.AuditEventListReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	c.writeEvents(r, req)
+}
+
+// ListMine 查询当前用户自己的审计事件
+func (c *AuditController) ListMine(r *ghttp.Request) {
+	identity := userIdentity(r, true, "服务令牌没有用户审计记录")
+	if identity == nil {
+		return
+	}
+
+	var req *model.AuditEventListReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 只能查询自己的事件，忽略请求中的用户过滤条件
+	req.UserId = identity.User.Id
+	req.Username = ""
+
+	c.writeEvents(r, req)
+}
+
+// writeEvents 查询并返回一页审计事件
+func (c *AuditController) writeEvents(r *ghttp.Request, req *model.AuditEventListReq) {
+	ctx := r.Context()
+
+	events, total, err := service.Audit.List(ctx, req)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to list audit events:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "获取审计记录失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"events":    events,
+			"total":     total,
+			"page":      req.Page,
+			"page_size": req.PageSize,
+		},
+	})
+}
@@ -3,11 +3,20 @@ package controller
 import (
 	"context-id-backend/internal/model"
 	"context-id-backend/internal/service"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
 )
 
+const (
+	refreshTokenCookie     = "refresh_token"
+	refreshTokenCookiePath = "/api/v1/auth"
+)
+
 type AuthController struct{}
 
 var Auth = &AuthController{}
@@ -28,6 +37,9 @@ func (c *AuthController) GetLoginURL(r *ghttp.Request) {
 	}
 
 	loginURL, _, err := service.Casdoor.GetLoginURL(ctx, redirectURI)
+	if c.writeRedirectError(r, err) || c.writeStateLimitError(r, err) {
+		return
+	}
 	if err != nil {
 		g.Log().Error(ctx, "Failed to generate login URL:", err)
 		r.Response.Status = 500
@@ -66,6 +78,9 @@ func (c *AuthController) GetSignupURL(r *ghttp.Request) {
 	// enablePassword = false: 完整OAuth2注册流程，注册后可以重定向
 	enablePassword := false
 	signupURL, _, err := service.Casdoor.GetSignupURL(ctx, enablePassword, redirectURI)
+	if c.writeRedirectError(r, err) || c.writeStateLimitError(r, err) {
+		return
+	}
 	if err != nil {
 		g.Log().Error(ctx, "Failed to generate signup URL:", err)
 		r.Response.Status = 500
@@ -89,13 +104,7 @@ func (c *AuthController) GetSignupURL(r *ghttp.Request) {
 func (c *AuthController) GetMyProfileURL(r *ghttp.Request) {
 	ctx := r.Context()
 
-	accessToken := r.GetHeader("Authorization")
-
-	// 移除 "Bearer " 前缀
-	if len(accessToken) > 7 && accessToken[:7] == "Bearer " {
-		accessToken = accessToken[7:]
-	}
-
+	accessToken := c.accessToken(r)
 	if accessToken == "" {
 		r.Response.Status = 400
 		r.Response.WriteJson(g.Map{
@@ -136,7 +145,19 @@ func (c *AuthController) Login(r *ghttp.Request) {
 		return
 	}
 
-	userInfo, token, err := service.Casdoor.HandleCallback(ctx, req.Code, req.State)
+	user, tokens, err := service.Casdoor.HandleCallback(ctx, req.Code, req.State, req.RedirectURI)
+	if c.writeRedirectError(r, err) {
+		return
+	}
+	if statusErr := service.AsUserStatusError(err); statusErr != nil {
+		r.Response.Status = 403
+		r.Response.WriteJson(g.Map{
+			"code":    403,
+			"message": statusErr.Message,
+			"error":   statusErr.Code,
+		})
+		return
+	}
 	if err != nil {
 		g.Log().Error(ctx, "Login failed:", err)
 		r.Response.Status = 500
@@ -147,23 +168,366 @@ func (c *AuthController) Login(r *ghttp.Request) {
 		return
 	}
 
+	// 刷新令牌通过HttpOnly Cookie下发，前端脚本无法读取
+	if tokens.RefreshToken != "" {
+		c.setRefreshCookie(r, tokens.RefreshToken)
+	}
+
+	// Cookie会话模式：访问令牌只保存在加密的HttpOnly Cookie中，响应中只返回CSRF令牌
+	// 服务端未启用bff时回退到token模式，客户端通过响应中的mode区分
+	if req.Mode == "cookie" && service.BFF.Enabled() {
+		session, err := c.startSession(r, tokens)
+		if err != nil {
+			g.Log().Error(ctx, "Failed to start cookie session:", err)
+			r.Response.Status = 500
+			r.Response.WriteJson(g.Map{
+				"code":    500,
+				"message": "创建会话失败: " + err.Error(),
+			})
+			return
+		}
+
+		r.Response.WriteJson(g.Map{
+			"code":    200,
+			"message": "登录成功",
+			"data": g.Map{
+				"mode":       "cookie",
+				"csrf_token": session.CSRFToken,
+				"expires_in": c.expiresIn(tokens),
+				"user":       user,
+			},
+		})
+		return
+	}
+
 	r.Response.WriteJson(g.Map{
 		"code":    200,
 		"message": "登录成功",
 		"data": g.Map{
-			"token": token,
-			"user":  userInfo,
+			"mode":          "token",
+			"token":         tokens.AccessToken,
+			"session_token": tokens.SessionToken, // 本地会话令牌，其他服务可通过/auth/jwks验证
+			"expires_in":    c.expiresIn(tokens),
+			"user":          user,
+		},
+	})
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌（刷新令牌每次使用后轮换）
+// 浏览器通过HttpOnly Cookie自动携带刷新令牌，其他客户端可在请求体中传入
+func (c *AuthController) Refresh(r *ghttp.Request) {
+	ctx := r.Context()
+
+	var req *model.RefreshTokenReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// Cookie会话模式下刷新同样由浏览器自动携带Cookie，需要通过CSRF校验
+	session, err := service.BFF.SessionFromRequest(r)
+	if err != nil {
+		g.Log().Warning(ctx, "Ignoring invalid session cookie on refresh:", err)
+		session = nil
+	}
+	if session != nil && !service.BFF.CheckCSRF(r, session) {
+		r.Response.Status = 403
+		r.Response.WriteJson(g.Map{
+			"code":    403,
+			"message": "CSRF校验失败",
+			"error":   "csrf_failed",
+		})
+		return
+	}
+
+	refreshToken := req.RefreshToken
+	if refreshToken == "" {
+		refreshToken = r.Cookie.Get(refreshTokenCookie).String()
+	}
+	if refreshToken == "" {
+		r.Response.Status = 401
+		r.Response.WriteJson(g.Map{
+			"code":    401,
+			"message": "缺少刷新令牌",
+		})
+		return
+	}
+
+	tokens, err := service.Casdoor.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		g.Log().Warning(ctx, "Token refresh failed:", err)
+
+		// 上游暂时失败（网络错误、5xx）时刷新令牌仍然有效，保留Cookie以便客户端重试
+		if !errors.Is(err, service.ErrRefreshTokenInvalid) && !errors.Is(err, service.ErrRefreshTokenReused) {
+			r.Response.Status = 502
+			r.Response.WriteJson(g.Map{
+				"code":    502,
+				"message": "刷新令牌失败，请稍后重试",
+			})
+			return
+		}
+
+		c.clearRefreshCookie(r)
+		c.clearSessionCookies(r)
+
+		message := "刷新令牌无效或已过期"
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			message = "刷新令牌已被使用，请重新登录"
+		}
+		r.Response.Status = 401
+		r.Response.WriteJson(g.Map{
+			"code":    401,
+			"message": message,
+		})
+		return
+	}
+
+	c.setRefreshCookie(r, tokens.RefreshToken)
+
+	// Cookie会话：用新的访问令牌更新会话Cookie，CSRF令牌保持不变
+	if session != nil {
+		session.AccessToken = tokens.AccessToken
+		if err := c.setSessionCookies(r, session, tokens); err != nil {
+			g.Log().Error(ctx, "Failed to renew cookie session:", err)
+			r.Response.Status = 500
+			r.Response.WriteJson(g.Map{
+				"code":    500,
+				"message": "更新会话失败: " + err.Error(),
+			})
+			return
+		}
+
+		r.Response.WriteJson(g.Map{
+			"code":    200,
+			"message": "success",
+			"data": g.Map{
+				"mode":       "cookie",
+				"csrf_token": session.CSRFToken,
+				"expires_in": c.expiresIn(tokens),
+			},
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"token":         tokens.AccessToken,
+			"session_token": tokens.SessionToken,
+			"expires_in":    c.expiresIn(tokens),
 		},
 	})
 }
 
+// Logout 登出当前会话：吊销当前访问令牌及其刷新令牌
+func (c *AuthController) Logout(r *ghttp.Request) {
+	c.logout(r, false)
+}
+
+// LogoutAll 登出所有会话：吊销当前用户的全部访问令牌和刷新令牌
+func (c *AuthController) LogoutAll(r *ghttp.Request) {
+	c.logout(r, true)
+}
+
+// logout 登出处理
+func (c *AuthController) logout(r *ghttp.Request, allSessions bool) {
+	ctx := r.Context()
+
+	accessToken := c.accessToken(r)
+	if accessToken == "" {
+		r.Response.Status = 401
+		r.Response.WriteJson(g.Map{
+			"code":    401,
+			"message": "缺少访问令牌",
+		})
+		return
+	}
+
+	// 服务令牌由调用方自行到期，不存在会话
+	if identity, _ := r.GetCtxVar("identity").Interface().(*service.Identity); identity != nil && identity.IsService() {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "服务令牌不能用于登出",
+		})
+		return
+	}
+
+	refreshToken := r.Cookie.Get(refreshTokenCookie).String()
+	err := service.Casdoor.Logout(ctx, accessToken, refreshToken, allSessions)
+	if errors.Is(err, service.ErrPersonalTokenLogout) {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "个人访问令牌不能用于登出，请通过 DELETE /api/v1/tokens/{id} 吊销",
+		})
+		return
+	}
+	if err != nil {
+		g.Log().Error(ctx, "Logout failed:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "登出失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.clearRefreshCookie(r)
+	c.clearSessionCookies(r)
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "已登出",
+		"data": g.Map{
+			"all_sessions": allSessions,
+		},
+	})
+}
+
+// writeRedirectError redirect_uri校验失败时返回结构化的400响应
+func (c *AuthController) writeRedirectError(r *ghttp.Request, err error) bool {
+	var redirectErr *service.RedirectURIError
+	if !errors.As(err, &redirectErr) {
+		return false
+	}
+
+	r.Response.Status = 400
+	r.Response.WriteJson(g.Map{
+		"code":    400,
+		"message": redirectErr.Reason,
+		"error":   redirectErr.Code,
+		"data": g.Map{
+			"redirect_uri": redirectErr.RedirectURI,
+		},
+	})
+	return true
+}
+
+// writeStateLimitError 未完成的登录请求达到上限时返回503，提示客户端稍后重试
+func (c *AuthController) writeStateLimitError(r *ghttp.Request, err error) bool {
+	if !errors.Is(err, service.ErrTooManyStates) {
+		return false
+	}
+
+	r.Response.Header().Set("Retry-After", "60")
+	r.Response.Status = 503
+	r.Response.WriteJson(g.Map{
+		"code":    503,
+		"message": "登录请求过多，请稍后再试",
+		"error":   "too_many_pending_logins",
+	})
+	return true
+}
+
+// bearerToken 从Authorization头中提取Bearer token
+func (c *AuthController) bearerToken(r *ghttp.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
+		return authHeader[7:]
+	}
+	return ""
+}
+
+// accessToken 取出访问令牌：Authorization头优先，其次为Cookie会话（启用bff时）
+func (c *AuthController) accessToken(r *ghttp.Request) string {
+	if token := c.bearerToken(r); token != "" {
+		return token
+	}
+	if session, err := service.BFF.SessionFromRequest(r); err == nil && session != nil {
+		return session.AccessToken
+	}
+	return ""
+}
+
+// startSession 登录成功后创建Cookie会话
+func (c *AuthController) startSession(r *ghttp.Request, tokens *service.OAuthTokens) (*service.BFFSession, error) {
+	session, err := service.BFF.NewSession(tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setSessionCookies(r, session, tokens); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// setSessionCookies 下发加密的会话Cookie（HttpOnly）和CSRF令牌Cookie（前端脚本可读）
+// 有刷新令牌时会话Cookie与刷新令牌同时过期，访问令牌过期后通过 /auth/refresh 更新
+func (c *AuthController) setSessionCookies(r *ghttp.Request, session *service.BFFSession, tokens *service.OAuthTokens) error {
+	value, err := service.BFF.Seal(session)
+	if err != nil {
+		return err
+	}
+
+	maxAge := service.Token.RefreshTTL()
+	if tokens.RefreshToken == "" && !tokens.ExpiresAt.IsZero() {
+		maxAge = time.Until(tokens.ExpiresAt)
+	}
+	options := ghttp.CookieOptions{
+		SameSite: http.SameSiteStrictMode,
+		Secure:   c.isSecureRequest(r),
+		HttpOnly: true,
+	}
+	r.Cookie.SetCookie(service.BFFSessionCookie, value, "", "/", maxAge, options)
+
+	options.HttpOnly = false
+	r.Cookie.SetCookie(service.BFFCSRFCookie, session.CSRFToken, "", "/", maxAge, options)
+	return nil
+}
+
+// clearSessionCookies 清除Cookie会话
+func (c *AuthController) clearSessionCookies(r *ghttp.Request) {
+	if !service.BFF.Enabled() {
+		return
+	}
+	r.Cookie.RemoveCookie(service.BFFSessionCookie, "", "/")
+	r.Cookie.RemoveCookie(service.BFFCSRFCookie, "", "/")
+}
+
+// setRefreshCookie 以HttpOnly Cookie下发刷新令牌，仅限认证接口路径
+func (c *AuthController) setRefreshCookie(r *ghttp.Request, refreshToken string) {
+	r.Cookie.SetCookie(refreshTokenCookie, refreshToken, "", refreshTokenCookiePath, service.Token.RefreshTTL(), ghttp.CookieOptions{
+		SameSite: http.SameSiteStrictMode,
+		Secure:   c.isSecureRequest(r),
+		HttpOnly: true,
+	})
+}
+
+// clearRefreshCookie 清除刷新令牌Cookie
+func (c *AuthController) clearRefreshCookie(r *ghttp.Request) {
+	r.Cookie.RemoveCookie(refreshTokenCookie, "", refreshTokenCookiePath)
+}
+
+// isSecureRequest 判断请求是否通过HTTPS到达（支持反向代理）
+func (c *AuthController) isSecureRequest(r *ghttp.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// expiresIn 计算访问令牌剩余有效秒数
+func (c *AuthController) expiresIn(tokens *service.OAuthTokens) int64 {
+	if tokens.ExpiresAt.IsZero() {
+		return 0
+	}
+	return int64(time.Until(tokens.ExpiresAt).Seconds())
+}
+
 // GetCurrentUser 获取当前用户信息 (类似tutorial中的/api/user，使用token验证)
 func (c *AuthController) GetCurrentUser(r *ghttp.Request) {
 	ctx := r.Context()
 
-	// 从Header获取token
+	// 从Header获取token，没有时使用Cookie会话
 	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
+	token := c.accessToken(r)
+	if token == "" && authHeader != "" {
+		token = authHeader
+	}
+	if token == "" {
 		r.Response.Status = 401
 		r.Response.WriteJson(g.Map{
 			"code":    401,
@@ -172,19 +536,91 @@ func (c *AuthController) GetCurrentUser(r *ghttp.Request) {
 		return
 	}
 
-	// 提取token（去掉"Bearer "前缀）
-	token := authHeader
-	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-		token = authHeader[7:]
+	// 验证token并获取用户信息
+	user, err := service.Casdoor.VerifyToken(ctx, token)
+	if err != nil {
+		response := g.Map{
+			"code":    401,
+			"message": "token无效",
+		}
+		if tokenErr := service.AsTokenError(err); tokenErr != nil {
+			response["message"] = tokenErr.Message
+			response["error"] = tokenErr.Code
+		}
+		r.Response.Status = 401
+		r.Response.WriteJson(response)
+		return
 	}
 
-	// 验证token并获取用户信息
-	userInfo, err := service.Casdoor.ValidateToken(ctx, token)
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"user": user,
+		},
+	})
+}
+
+// IssueServiceToken OAuth2 client_credentials令牌端点（代理到Casdoor，仅限已登记的服务客户端）
+// 为兼容标准OAuth2客户端库，响应和错误使用RFC 6749格式
+func (c *AuthController) IssueServiceToken(r *ghttp.Request) {
+	ctx := r.Context()
+
+	grantType := r.Get("grant_type").String()
+	if grantType != "client_credentials" {
+		c.writeOAuthError(r, 400, "unsupported_grant_type", "仅支持client_credentials")
+		return
+	}
+
+	// 客户端凭证可以通过HTTP Basic认证或请求参数传入
+	clientId, clientSecret, ok := r.Request.BasicAuth()
+	if !ok {
+		clientId = r.Get("client_id").String()
+		clientSecret = r.Get("client_secret").String()
+	}
+	if clientId == "" || clientSecret == "" {
+		c.writeOAuthError(r, 400, "invalid_request", "缺少client_id或client_secret")
+		return
+	}
+
+	token, principal, err := service.ServiceClient.IssueToken(ctx, clientId, clientSecret)
+	if errors.Is(err, service.ErrServiceClientUnknown) || errors.Is(err, service.ErrServiceClientCredentials) {
+		c.writeOAuthError(r, 401, "invalid_client", "客户端认证失败")
+		return
+	}
 	if err != nil {
+		g.Log().Error(ctx, "Failed to issue service token:", err)
+		c.writeOAuthError(r, 500, "server_error", "签发令牌失败")
+		return
+	}
+
+	r.Response.Header().Set("Cache-Control", "no-store")
+	r.Response.WriteJson(g.Map{
+		"access_token": token.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   c.expiresIn(&service.OAuthTokens{ExpiresAt: token.Expiry}),
+		"scope":        strings.Join(principal.Scopes, " "),
+	})
+}
+
+// writeOAuthError 返回RFC 6749格式的错误
+func (c *AuthController) writeOAuthError(r *ghttp.Request, status int, code, description string) {
+	r.Response.Status = status
+	r.Response.Header().Set("Cache-Control", "no-store")
+	r.Response.WriteJson(g.Map{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// GetPermissions 返回当前用户在Casdoor中的角色和权限，前端可据此控制界面展示
+func (c *AuthController) GetPermissions(r *ghttp.Request) {
+	identity, _ := r.GetCtxVar("identity").Interface().(*service.Identity)
+	if identity == nil {
 		r.Response.Status = 401
 		r.Response.WriteJson(g.Map{
 			"code":    401,
-			"message": "token无效",
+			"message": "未提供认证信息",
 		})
 		return
 	}
@@ -193,7 +629,14 @@ func (c *AuthController) GetCurrentUser(r *ghttp.Request) {
 		"code":    200,
 		"message": "success",
 		"data": g.Map{
-			"user": userInfo,
+			"roles":       identity.Roles,
+			"permissions": identity.Permissions,
+			"isAdmin":     identity.IsAdministrator(),
 		},
 	})
 }
+
+// GetJWKS 返回本地会话Token的签名公钥（JWK Set），供其他服务离线验证
+func (c *AuthController) GetJWKS(r *ghttp.Request) {
+	r.Response.WriteJson(service.Token.JWKS())
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"context-id-backend/internal/model"
+	"context-id-backend/internal/service"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+const (
+	// deviceBindingCookie 确认设备授权的浏览器绑定Cookie，Casdoor回调时校验
+	deviceBindingCookie     = "cid_device"
+	deviceBindingCookiePath = "/api/v1/auth/device"
+)
+
+type DeviceController struct{}
+
+var Device = &DeviceController{}
+
+// Code 设备授权请求（RFC 8628 3.1），CLI获取device_code和user_code
+func (c *DeviceController) Code(r *ghttp.Request) {
+	ctx := r.Context()
+
+	response, err := service.Casdoor.StartDeviceAuthorization(ctx)
+	if err != nil {
+		Auth.writeOAuthError(r, 500, "server_error", "创建设备授权失败")
+		return
+	}
+
+	r.Response.Header().Set("Cache-Control", "no-store")
+	r.Response.WriteJson(response)
+}
+
+// Confirm 用户在浏览器中输入用户码后，返回确认页展示的用户码、发起请求的客户端和CSRF令牌，
+// 同时下发绑定当前浏览器的Cookie（RFC 8628 5.4：用户需核对后再登录）
+func (c *DeviceController) Confirm(r *ghttp.Request) {
+	ctx := r.Context()
+
+	userCode := r.Get("user_code").String()
+	if userCode == "" {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "缺少用户码",
+		})
+		return
+	}
+
+	confirmation, binding, err := service.Casdoor.ConfirmDeviceAuthorization(ctx, userCode)
+	if err != nil {
+		g.Log().Warning(ctx, "Device confirmation failed:", err)
+		status := 400
+		message := "用户码无效或已使用"
+		if errors.Is(err, service.ErrDeviceExpiredToken) {
+			message = "用户码已过期，请在命令行中重新登录"
+		} else if !errors.Is(err, service.ErrDeviceInvalidGrant) {
+			status = 500
+			message = "验证失败，请稍后重试"
+		}
+		r.Response.Status = status
+		r.Response.WriteJson(g.Map{
+			"code":    status,
+			"message": message,
+		})
+		return
+	}
+
+	// Casdoor回调是跨站的顶层跳转，SameSite=Lax的Cookie会随回调带回
+	r.Cookie.SetCookie(deviceBindingCookie, binding, "", deviceBindingCookiePath, time.Duration(confirmation.ExpiresIn)*time.Second, ghttp.CookieOptions{
+		SameSite: http.SameSiteLaxMode,
+		Secure:   Auth.isSecureRequest(r),
+		HttpOnly: true,
+	})
+	r.Response.Header().Set("Cache-Control", "no-store")
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data":    confirmation,
+	})
+}
+
+// Verify 用户在确认页确认后（POST表单携带用户码和CSRF令牌）跳转到Casdoor登录
+func (c *DeviceController) Verify(r *ghttp.Request) {
+	ctx := r.Context()
+
+	userCode := r.GetForm("user_code").String()
+	csrfToken := r.GetForm("csrf_token").String()
+	if userCode == "" || csrfToken == "" {
+		c.redirectResult(r, "error", "请在设备登录页面中输入并确认用户码")
+		return
+	}
+
+	binding := r.Cookie.Get(deviceBindingCookie).String()
+	loginURL, err := service.Casdoor.GetDeviceLoginURL(ctx, userCode, csrfToken, binding)
+	if err != nil {
+		g.Log().Warning(ctx, "Device verification failed:", err)
+		message := "用户码无效或已使用"
+		if errors.Is(err, service.ErrDeviceVerificationFailed) {
+			message = "确认已失效，请重新输入用户码"
+		} else if errors.Is(err, service.ErrDeviceExpiredToken) {
+			message = "用户码已过期，请在命令行中重新登录"
+		} else if !errors.Is(err, service.ErrDeviceInvalidGrant) {
+			message = "验证失败，请稍后重试"
+		}
+		c.redirectResult(r, "error", message)
+		return
+	}
+
+	r.Response.RedirectTo(loginURL, http.StatusSeeOther)
+}
+
+// Callback Casdoor登录完成后的回调，批准设备授权后跳转到结果页面
+// 回调必须携带确认授权时下发的浏览器绑定Cookie
+func (c *DeviceController) Callback(r *ghttp.Request) {
+	ctx := r.Context()
+
+	binding := r.Cookie.Get(deviceBindingCookie).String()
+	r.Cookie.RemoveCookie(deviceBindingCookie, "", deviceBindingCookiePath)
+
+	err := service.Casdoor.HandleDeviceCallback(ctx, r.Get("code").String(), r.Get("state").String(), binding)
+	if errors.Is(err, service.ErrDeviceAccessDenied) {
+		c.redirectResult(r, "denied", "已取消授权")
+		return
+	}
+	if statusErr := service.AsUserStatusError(err); statusErr != nil {
+		c.redirectResult(r, "denied", statusErr.Message)
+		return
+	}
+	if err != nil {
+		g.Log().Error(ctx, "Device callback failed:", err)
+		c.redirectResult(r, "error", "授权失败，请在命令行中重新登录")
+		return
+	}
+
+	c.redirectResult(r, "approved", "授权成功，请返回命令行")
+}
+
+// Token 设备令牌轮询（RFC 8628 3.4），错误码遵循RFC 8628 3.5
+func (c *DeviceController) Token(r *ghttp.Request) {
+	ctx := r.Context()
+
+	var req *model.DeviceTokenReq
+	if err := r.Parse(&req); err != nil {
+		Auth.writeOAuthError(r, 400, "invalid_request", err.Error())
+		return
+	}
+	if req.GrantType != service.DeviceGrantType {
+		Auth.writeOAuthError(r, 400, "unsupported_grant_type", "grant_type必须为"+service.DeviceGrantType)
+		return
+	}
+	if req.DeviceCode == "" {
+		Auth.writeOAuthError(r, 400, "invalid_request", "缺少device_code")
+		return
+	}
+
+	tokens, err := service.Casdoor.PollDeviceToken(ctx, req.DeviceCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrDeviceAuthorizationPending):
+			Auth.writeOAuthError(r, 400, "authorization_pending", "等待用户完成授权")
+		case errors.Is(err, service.ErrDeviceSlowDown):
+			Auth.writeOAuthError(r, 400, "slow_down", "轮询过于频繁，请增加5秒轮询间隔")
+		case errors.Is(err, service.ErrDeviceAccessDenied):
+			Auth.writeOAuthError(r, 400, "access_denied", "用户拒绝了授权")
+		case errors.Is(err, service.ErrDeviceExpiredToken):
+			Auth.writeOAuthError(r, 400, "expired_token", "device_code已过期")
+		case errors.Is(err, service.ErrDeviceInvalidGrant):
+			Auth.writeOAuthError(r, 400, "invalid_grant", "device_code无效")
+		default:
+			g.Log().Error(ctx, "Device token poll failed:", err)
+			Auth.writeOAuthError(r, 500, "server_error", "获取令牌失败")
+		}
+		return
+	}
+
+	r.Response.Header().Set("Cache-Control", "no-store")
+	r.Response.WriteJson(g.Map{
+		"access_token":  tokens.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    Auth.expiresIn(tokens),
+		"refresh_token": tokens.RefreshToken,
+	})
+}
+
+// redirectResult 跳转到设备登录页面显示结果
+func (c *DeviceController) redirectResult(r *ghttp.Request, status, message string) {
+	r.Response.RedirectTo("/device?status=" + url.QueryEscape(status) + "&message=" + url.QueryEscape(message))
+}
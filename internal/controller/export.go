@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context-id-backend/internal/model"
+	"context-id-backend/internal/service"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// exportDenied 服务令牌和个人访问令牌导出个人数据时的提示，导出必须由用户本人登录后操作
+const exportDenied = "服务令牌和个人访问令牌不能导出个人数据，请登录后操作"
+
+type ExportController struct{}
+
+var Export = &ExportController{}
+
+// Export 导出当前用户的个人数据
+// 数据量较小时直接返回zip；数据量较大或请求带async=true时异步生成，返回202和有时效的下载链接
+func (c *ExportController) Export(r *ghttp.Request) {
+	ctx := r.Context()
+
+	identity := userIdentity(r, false, exportDenied)
+	if identity == nil {
+		return
+	}
+	user := identity.User
+
+	async := r.Get("async").Bool()
+	if !async {
+		needsAsync, err := service.Export.NeedsAsync(ctx, user)
+		if err != nil {
+			c.writeError(r, err)
+			return
+		}
+		async = needsAsync
+	}
+	if async {
+		c.start(r, user)
+		return
+	}
+
+	archive, err := service.Export.Build(ctx, user)
+	if err != nil {
+		c.writeError(r, err)
+		return
+	}
+	c.writeArchive(r, user.Id, archive)
+}
+
+// Get 查询异步导出的状态
+func (c *ExportController) Get(r *ghttp.Request) {
+	identity := userIdentity(r, false, exportDenied)
+	if identity == nil {
+		return
+	}
+	user := identity.User
+
+	export, err := service.Export.Get(r.Context(), user.Id, r.Get("id").Uint64())
+	if err != nil {
+		c.writeError(r, err)
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"export": export,
+		},
+	})
+}
+
+// Download 通过下载链接下载异步生成的导出包（链接中的令牌即凭证，不需要登录）
+func (c *ExportController) Download(r *ghttp.Request) {
+	export, err := service.Export.Download(r.Context(), r.Get("id").Uint64(), r.Get("token").String())
+	if err != nil {
+		c.writeError(r, err)
+		return
+	}
+	c.writeArchive(r, export.UserId, export.Archive)
+}
+
+// start 创建异步导出
+func (c *ExportController) start(r *ghttp.Request, user *model.User) {
+	export, token, err := service.Export.Start(r.Context(), user)
+	if err != nil {
+		c.writeError(r, err)
+		return
+	}
+
+	r.Response.Status = 202
+	r.Response.WriteJson(g.Map{
+		"code":    202,
+		"message": "导出包正在生成，完成后可通过下载链接下载",
+		"data": g.Map{
+			"export":       export,
+			"status_url":   fmt.Sprintf("/api/v1/user/exports/%d", export.Id),
+			"download_url": fmt.Sprintf("/api/v1/user/exports/%d/download?token=%s", export.Id, url.QueryEscape(token)),
+		},
+	})
+}
+
+// writeArchive 以附件形式返回导出包
+func (c *ExportController) writeArchive(r *ghttp.Request, userId uint64, archive []byte) {
+	filename := fmt.Sprintf("personal-data-%d-%s.zip", userId, gtime.Now().Format("Ymd"))
+	r.Response.Header().Set("Content-Type", "application/zip")
+	r.Response.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	r.Response.Header().Set("Cache-Control", "no-store")
+	r.Response.Write(archive)
+}
+
+// writeError 将导出错误转换为对应的状态码
+func (c *ExportController) writeError(r *ghttp.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrExportNotFound):
+		r.Response.Status = 404
+		r.Response.WriteJson(g.Map{
+			"code":    404,
+			"message": "导出不存在或下载链接已过期",
+			"error":   "export_not_found",
+		})
+	case errors.Is(err, service.ErrExportNotReady):
+		r.Response.Status = 409
+		r.Response.WriteJson(g.Map{
+			"code":    409,
+			"message": "导出包尚未生成完成或生成失败",
+			"error":   "export_not_ready",
+		})
+	case errors.Is(err, service.ErrExportInProgress):
+		r.Response.Status = 409
+		r.Response.WriteJson(g.Map{
+			"code":    409,
+			"message": "已有正在生成的导出，请稍后再试",
+			"error":   "export_in_progress",
+		})
+	default:
+		g.Log().Error(r.Context(), "Data export failed:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "导出个人数据失败",
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"context-id-backend/internal/service"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// userIdentity 获取以用户身份认证的当前调用方：未认证返回401，服务令牌返回403并返回nil；
+// allowPersonalToken为false时个人访问令牌同样返回403（删除账号、管理令牌等须由用户本人登录后操作）
+// denied为拒绝时返回给调用方的提示
+func userIdentity(r *ghttp.Request, allowPersonalToken bool, denied string) *service.Identity {
+	identity, _ := r.GetCtxVar("identity").Interface().(*service.Identity)
+	if identity == nil {
+		r.Response.Status = 401
+		r.Response.WriteJson(g.Map{
+			"code":    401,
+			"message": "未提供认证信息",
+		})
+		return nil
+	}
+	if identity.IsService() || identity.User == nil || (!allowPersonalToken && identity.IsPersonalToken()) {
+		r.Response.Status = 403
+		r.Response.WriteJson(g.Map{
+			"code":    403,
+			"message": denied,
+			"error":   "forbidden",
+		})
+		return nil
+	}
+	return identity
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context-id-backend/internal/model"
+	"context-id-backend/internal/service"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// sessionDenied 服务令牌查询或结束会话时的提示，服务调用方没有登录会话
+const sessionDenied = "服务令牌没有登录会话"
+
+type SessionController struct{}
+
+var Session = &SessionController{}
+
+// List 列出当前用户的活跃登录会话，标记发起请求的会话
+func (c *SessionController) List(r *ghttp.Request) {
+	ctx := r.Context()
+
+	identity := userIdentity(r, true, sessionDenied)
+	if identity == nil {
+		return
+	}
+
+	sessions, err := service.Session.List(ctx, identity.User.Id)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to list sessions:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "获取会话列表失败",
+		})
+		return
+	}
+
+	items := make([]*model.UserSessionRes, 0, len(sessions))
+	for _, session := range sessions {
+		items = append(items, &model.UserSessionRes{
+			UserSession: session,
+			Current:     identity.SessionId != 0 && session.Id == identity.SessionId,
+		})
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"sessions": items,
+		},
+	})
+}
+
+// Terminate 结束当前用户的指定会话，该会话的访问令牌和刷新令牌随即失效
+func (c *SessionController) Terminate(r *ghttp.Request) {
+	ctx := r.Context()
+
+	identity := userIdentity(r, true, sessionDenied)
+	if identity == nil {
+		return
+	}
+
+	id := r.Get("id").Uint64()
+	err := service.Session.Terminate(ctx, identity.User.Id, id)
+	if errors.Is(err, service.ErrSessionNotFound) {
+		r.Response.Status = 404
+		r.Response.WriteJson(g.Map{
+			"code":    404,
+			"message": "会话不存在或已结束",
+		})
+		return
+	}
+	if err != nil {
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "结束会话失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "会话已结束",
+	})
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context-id-backend/internal/model"
+	"context-id-backend/internal/service"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// tokenDenied 个人访问令牌和服务令牌不能管理令牌，防止泄露的令牌自我续期
+const tokenDenied = "个人访问令牌不能用于管理令牌，请通过登录会话操作"
+
+type TokenController struct{}
+
+var Token = &TokenController{}
+
+// Create 创建个人访问令牌，明文令牌只在响应中返回一次
+func (c *TokenController) Create(r *ghttp.Request) {
+	ctx := r.Context()
+
+	identity := userIdentity(r, false, tokenDenied)
+	if identity == nil {
+		return
+	}
+
+	var req *model.PersonalAccessTokenCreateReq
+	if err := r.Parse(&req); err != nil {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	secret, token, err := service.PersonalToken.Create(ctx, identity.User, req)
+	if errors.Is(err, service.ErrPersonalTokenScope) || errors.Is(err, service.ErrPersonalTokenTTL) {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "创建令牌失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "令牌创建成功，请立即保存，之后将无法再次查看",
+		"data": g.Map{
+			"token":                 secret,
+			"personal_access_token": token,
+		},
+	})
+}
+
+// List 列出当前用户的个人访问令牌（不包含明文令牌）
+func (c *TokenController) List(r *ghttp.Request) {
+	ctx := r.Context()
+
+	identity := userIdentity(r, false, tokenDenied)
+	if identity == nil {
+		return
+	}
+
+	tokens, err := service.PersonalToken.List(ctx, identity.User.Id)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to list personal access tokens:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "获取令牌列表失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data": g.Map{
+			"personal_access_tokens": tokens,
+		},
+	})
+}
+
+// Revoke 吊销当前用户的个人访问令牌
+func (c *TokenController) Revoke(r *ghttp.Request) {
+	ctx := r.Context()
+
+	identity := userIdentity(r, false, tokenDenied)
+	if identity == nil {
+		return
+	}
+
+	id := r.Get("id").Uint64()
+	err := service.PersonalToken.Revoke(ctx, identity.User.Id, id)
+	if errors.Is(err, service.ErrPersonalTokenNotFound) {
+		r.Response.Status = 404
+		r.Response.WriteJson(g.Map{
+			"code":    404,
+			"message": "令牌不存在或已被吊销",
+		})
+		return
+	}
+	if err != nil {
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "吊销令牌失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "令牌已吊销",
+	})
+}
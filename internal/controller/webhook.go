@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context-id-backend/internal/service"
+	"errors"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+type WebhookController struct{}
+
+var Webhook = &WebhookController{}
+
+// Casdoor 接收Casdoor的Webhook事件（需携带共享密钥或HMAC签名），同步用户注册、资料变更和删除
+func (c *WebhookController) Casdoor(r *ghttp.Request) {
+	ctx := r.Context()
+
+	if !service.Webhook.Enabled() {
+		r.Response.Status = 404
+		r.Response.WriteJson(g.Map{
+			"code":    404,
+			"message": "Webhook未启用",
+		})
+		return
+	}
+
+	body := r.GetBody()
+	err := service.Webhook.Verify(
+		r.Header.Get(service.WebhookTokenHeader),
+		r.Header.Get(service.WebhookTimestampHeader),
+		r.Header.Get(service.WebhookSignatureHeader),
+		body,
+	)
+	if errors.Is(err, service.ErrWebhookPayload) {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "无法解析的事件内容",
+		})
+		return
+	}
+	if err != nil {
+		g.Log().Warning(ctx, "Rejected webhook request:", r.GetClientIp(), err)
+		r.Response.Status = 401
+		r.Response.WriteJson(g.Map{
+			"code":    401,
+			"message": "签名无效或已过期",
+			"error":   "invalid_signature",
+		})
+		return
+	}
+
+	result, err := service.Webhook.HandleCasdoor(ctx, body)
+	if errors.Is(err, service.ErrWebhookPayload) {
+		r.Response.Status = 400
+		r.Response.WriteJson(g.Map{
+			"code":    400,
+			"message": "无法解析的事件内容",
+		})
+		return
+	}
+	if err != nil {
+		// 返回5xx让发送方重试
+		g.Log().Error(ctx, "Failed to handle casdoor webhook:", err)
+		r.Response.Status = 500
+		r.Response.WriteJson(g.Map{
+			"code":    500,
+			"message": "事件处理失败",
+		})
+		return
+	}
+
+	r.Response.WriteJson(g.Map{
+		"code":    200,
+		"message": "success",
+		"data":    result,
+	})
+}
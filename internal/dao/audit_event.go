@@ -0,0 +1,51 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type AuditEventDao struct{}
+
+var AuditEvent = &AuditEventDao{}
+
+// Create 写入审计事件
+func (d *AuditEventDao) Create(ctx context.Context, event *model.AuditEvent) error {
+	_, err := g.DB().Model("audit_events").FieldsEx("id").Data(event).Insert()
+	return err
+}
+
+// List 按条件分页查询审计事件（按时间倒序），返回当前页和总数
+func (d *AuditEventDao) List(ctx context.Context, req *model.AuditEventListReq) ([]*model.AuditEvent, int, error) {
+	query := g.DB().Model("audit_events")
+	if req.EventType != "" {
+		query = query.Where("event_type", req.EventType)
+	}
+	if req.Username != "" {
+		query = query.Where("username", req.Username)
+	}
+	if req.UserId != 0 {
+		query = query.Where("user_id", req.UserId)
+	}
+	if req.Success != nil {
+		query = query.Where("success", *req.Success)
+	}
+	if req.Since != nil {
+		query = query.WhereGTE("created_at", req.Since)
+	}
+	if req.Until != nil {
+		query = query.WhereLT("created_at", req.Until)
+	}
+
+	var (
+		events []*model.AuditEvent
+		total  int
+	)
+	err := query.OrderDesc("id").Page(req.Page, req.PageSize).ScanAndCount(&events, &total, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
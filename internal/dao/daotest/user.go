@@ -0,0 +1,342 @@
+// Package daotest 存储接口的契约测试，数据库实现和内存实现运行同一套用例，保证行为一致
+package daotest
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// TestUserRepository 对UserRepository实现运行契约测试
+// newRepo为每个子测试返回一个空的存储（数据库实现需在其中清空users表）
+func TestUserRepository(t *testing.T, newRepo func(t *testing.T) dao.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepo(t)
+		user := newUser("alice")
+		if err := repo.Create(ctx, user); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if user.Id == 0 {
+			t.Fatal("Create did not set id")
+		}
+
+		lookups := map[string]func() (*model.User, error){
+			"GetById":        func() (*model.User, error) { return repo.GetById(ctx, user.Id) },
+			"GetByUsername":  func() (*model.User, error) { return repo.GetByUsername(ctx, "alice") },
+			"GetByEmail":     func() (*model.User, error) { return repo.GetByEmail(ctx, "alice@example.com") },
+			"GetByCasdoorId": func() (*model.User, error) { return repo.GetByCasdoorId(ctx, "casdoor-alice") },
+		}
+		for name, lookup := range lookups {
+			got, err := lookup()
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			assertUser(t, name, got, user)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		lookups := map[string]func() (*model.User, error){
+			"GetById":        func() (*model.User, error) { return repo.GetById(ctx, 404) },
+			"GetByUsername":  func() (*model.User, error) { return repo.GetByUsername(ctx, "nobody") },
+			"GetByEmail":     func() (*model.User, error) { return repo.GetByEmail(ctx, "nobody@example.com") },
+			"GetByCasdoorId": func() (*model.User, error) { return repo.GetByCasdoorId(ctx, "casdoor-nobody") },
+		}
+		for name, lookup := range lookups {
+			got, err := lookup()
+			if err != nil || got != nil {
+				t.Fatalf("%s: want nil, nil; got %v, %v", name, got, err)
+			}
+		}
+	})
+
+	t.Run("UniqueConstraints", func(t *testing.T) {
+		repo := newRepo(t)
+		mustCreate(t, repo, newUser("alice"))
+
+		sameUsername := newUser("alice")
+		sameUsername.Email = "other@example.com"
+		sameUsername.CasdoorId = "casdoor-other"
+		if err := repo.Create(ctx, sameUsername); err == nil {
+			t.Fatal("Create with duplicate username succeeded")
+		}
+
+		sameEmail := newUser("bob")
+		sameEmail.Email = "alice@example.com"
+		if err := repo.Create(ctx, sameEmail); err == nil {
+			t.Fatal("Create with duplicate email succeeded")
+		}
+
+		sameCasdoorId := newUser("carol")
+		sameCasdoorId.CasdoorId = "casdoor-alice"
+		if err := repo.Create(ctx, sameCasdoorId); err == nil {
+			t.Fatal("Create with duplicate casdoor id succeeded")
+		}
+	})
+
+	t.Run("EmptyCasdoorId", func(t *testing.T) {
+		repo := newRepo(t)
+		alice := newUser("alice")
+		alice.CasdoorId = ""
+		mustCreate(t, repo, alice)
+		bob := newUser("bob")
+		bob.CasdoorId = ""
+		mustCreate(t, repo, bob)
+
+		got, err := repo.GetByCasdoorId(ctx, "")
+		if err != nil || got != nil {
+			t.Fatalf("GetByCasdoorId(\"\"): want nil, nil; got %v, %v", got, err)
+		}
+
+		carol := mustCreate(t, repo, newUser("carol"))
+		carol.CasdoorId = ""
+		if err := repo.Update(ctx, carol); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err = repo.GetByCasdoorId(ctx, "casdoor-carol")
+		if err != nil {
+			t.Fatalf("GetByCasdoorId after update: %v", err)
+		}
+		carol.CasdoorId = "casdoor-carol"
+		assertUser(t, "GetByCasdoorId after update", got, carol)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repo := newRepo(t)
+		alice := mustCreate(t, repo, newUser("alice"))
+		bob := mustCreate(t, repo, newUser("bob"))
+
+		alice.DisplayName = "Alice Liddell"
+		alice.Email = "liddell@example.com"
+		if err := repo.Update(ctx, alice); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := repo.GetById(ctx, alice.Id)
+		if err != nil {
+			t.Fatalf("GetById: %v", err)
+		}
+		assertUser(t, "GetById after Update", got, alice)
+		if old, _ := repo.GetByEmail(ctx, "alice@example.com"); old != nil {
+			t.Fatal("old email still resolves after Update")
+		}
+
+		bob.Email = alice.Email
+		if err := repo.Update(ctx, bob); err == nil {
+			t.Fatal("Update to another user's email succeeded")
+		}
+	})
+
+	t.Run("UpdateKeepsStatus", func(t *testing.T) {
+		repo := newRepo(t)
+		user := mustCreate(t, repo, newUser("alice"))
+		user.Status = model.UserStatusSuspended
+		user.DisplayName = "Alice Liddell"
+		if err := repo.Update(ctx, user); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		got, err := repo.GetById(ctx, user.Id)
+		if err != nil {
+			t.Fatalf("GetById: %v", err)
+		}
+		if got.Status != model.UserStatusActive || got.DisplayName != "Alice Liddell" {
+			t.Fatalf("Update changed status or lost profile: status = %d, displayName = %q", got.Status, got.DisplayName)
+		}
+	})
+
+	t.Run("UpdateStatus", func(t *testing.T) {
+		repo := newRepo(t)
+		user := mustCreate(t, repo, newUser("alice"))
+		ok, err := repo.UpdateStatus(ctx, user.Id, model.UserStatusActive, model.UserStatusSuspended, "abuse")
+		if err != nil || !ok {
+			t.Fatalf("UpdateStatus: %t, %v", ok, err)
+		}
+		got, err := repo.GetById(ctx, user.Id)
+		if err != nil {
+			t.Fatalf("GetById: %v", err)
+		}
+		if got.Status != model.UserStatusSuspended || got.StatusReason != "abuse" || got.StatusChangedAt == nil {
+			t.Fatalf("got status %d, reason %q, changedAt %v", got.Status, got.StatusReason, got.StatusChangedAt)
+		}
+
+		// 当前状态与from不一致（并发修改）时不更新
+		ok, err = repo.UpdateStatus(ctx, user.Id, model.UserStatusActive, model.UserStatusDeleted, "stale")
+		if err != nil || ok {
+			t.Fatalf("UpdateStatus with stale from: %t, %v", ok, err)
+		}
+		if got, _ := repo.GetById(ctx, user.Id); got.Status != model.UserStatusSuspended {
+			t.Fatalf("status = %d after stale UpdateStatus, want %d", got.Status, model.UserStatusSuspended)
+		}
+
+		if ok, err := repo.UpdateStatus(ctx, 404, model.UserStatusActive, model.UserStatusSuspended, ""); err != nil || ok {
+			t.Fatalf("UpdateStatus on missing user: %t, %v", ok, err)
+		}
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repo := newRepo(t)
+		alice := mustCreate(t, repo, newUser("alice"))
+		mustCreate(t, repo, newUser("bob"))
+
+		ok, err := repo.SoftDelete(ctx, alice.Id)
+		if err != nil || !ok {
+			t.Fatalf("SoftDelete: %t, %v", ok, err)
+		}
+		if ok, err := repo.SoftDelete(ctx, alice.Id); err != nil || ok {
+			t.Fatalf("SoftDelete twice: %t, %v", ok, err)
+		}
+
+		// 已删除的用户不能被查询和更新
+		if got, err := repo.GetById(ctx, alice.Id); err != nil || got != nil {
+			t.Fatalf("GetById after SoftDelete: got %v, %v", got, err)
+		}
+		if got, err := repo.GetByUsername(ctx, "alice"); err != nil || got != nil {
+			t.Fatalf("GetByUsername after SoftDelete: got %v, %v", got, err)
+		}
+		if all, err := repo.ListAll(ctx); err != nil || len(all) != 1 || all[0].Username != "bob" {
+			t.Fatalf("ListAll after SoftDelete: got %d users, %v", len(all), err)
+		}
+		if ok, err := repo.UpdateStatus(ctx, alice.Id, model.UserStatusActive, model.UserStatusSuspended, ""); err != nil || ok {
+			t.Fatalf("UpdateStatus after SoftDelete: %t, %v", ok, err)
+		}
+
+		// 用户名、邮箱和Casdoor用户ID可以被新用户使用
+		again := mustCreate(t, repo, newUser("alice"))
+		if got, _ := repo.GetByEmail(ctx, "alice@example.com"); got == nil || got.Id != again.Id {
+			t.Fatalf("GetByEmail after re-create: got %v, want id %d", got, again.Id)
+		}
+
+		// 删除时间早于before且未匿名化的用户才需要匿名化
+		if pending, err := repo.ListToAnonymize(ctx, gtime.Now().Add(-time.Hour), 10); err != nil || len(pending) != 0 {
+			t.Fatalf("ListToAnonymize within grace period: got %d users, %v", len(pending), err)
+		}
+		pending, err := repo.ListToAnonymize(ctx, gtime.Now().Add(time.Second), 10)
+		if err != nil || len(pending) != 1 || pending[0].Id != alice.Id {
+			t.Fatalf("ListToAnonymize: got %d users, %v", len(pending), err)
+		}
+		if err := repo.Anonymize(ctx, alice.Id); err != nil {
+			t.Fatalf("Anonymize: %v", err)
+		}
+		if pending, err := repo.ListToAnonymize(ctx, gtime.Now().Add(time.Second), 10); err != nil || len(pending) != 0 {
+			t.Fatalf("ListToAnonymize after Anonymize: got %d users, %v", len(pending), err)
+		}
+
+		// 未删除的用户不会被匿名化
+		if err := repo.Anonymize(ctx, again.Id); err != nil {
+			t.Fatalf("Anonymize active user: %v", err)
+		}
+		if got, _ := repo.GetById(ctx, again.Id); got == nil || got.Email != "alice@example.com" {
+			t.Fatalf("Anonymize changed an active user: %v", got)
+		}
+	})
+
+	t.Run("ReturnedUserIsCopy", func(t *testing.T) {
+		repo := newRepo(t)
+		user := mustCreate(t, repo, newUser("alice"))
+		got, _ := repo.GetById(ctx, user.Id)
+		got.DisplayName = "changed"
+		again, _ := repo.GetById(ctx, user.Id)
+		if again.DisplayName != user.DisplayName {
+			t.Fatal("modifying a returned user changed the stored user")
+		}
+	})
+
+	t.Run("ListAndSearch", func(t *testing.T) {
+		repo := newRepo(t)
+		for i := 1; i <= 5; i++ {
+			user := newUser(fmt.Sprintf("user%d", i))
+			if i == 3 {
+				user.DisplayName = "Search Target"
+			}
+			if i%2 == 0 {
+				user.Status = model.UserStatusSuspended
+			}
+			mustCreate(t, repo, user)
+		}
+
+		all, err := repo.ListAll(ctx)
+		if err != nil || len(all) != 5 {
+			t.Fatalf("ListAll: got %d users, %v", len(all), err)
+		}
+		for i := 1; i < len(all); i++ {
+			if all[i-1].Id >= all[i].Id {
+				t.Fatal("ListAll is not ordered by id")
+			}
+		}
+
+		page, total, err := repo.List(ctx, &model.UserListReq{Page: 2, PageSize: 2})
+		if err != nil || total != 5 || len(page) != 2 || page[0].Id != all[2].Id {
+			t.Fatalf("List page 2: got %d users, total %d, %v", len(page), total, err)
+		}
+
+		found, total, err := repo.List(ctx, &model.UserListReq{Keyword: "search target", Page: 1, PageSize: 10})
+		if err != nil || total != 1 || len(found) != 1 || found[0].Username != "user3" {
+			t.Fatalf("List by keyword: got %d users, total %d, %v", len(found), total, err)
+		}
+
+		status := model.UserStatusSuspended
+		suspended, total, err := repo.List(ctx, &model.UserListReq{Status: &status, Page: 1, PageSize: 10})
+		if err != nil || total != 2 || len(suspended) != 2 {
+			t.Fatalf("List by status: got %d users, total %d, %v", len(suspended), total, err)
+		}
+
+		sorted, _, err := repo.List(ctx, &model.UserListReq{Sort: "-username", Page: 1, PageSize: 10})
+		if err != nil || len(sorted) != 5 || sorted[0].Username != "user5" || sorted[4].Username != "user1" {
+			t.Fatalf("List sorted by -username: got %d users, %v", len(sorted), err)
+		}
+
+		byEmail, total, err := repo.List(ctx, &model.UserListReq{Email: "user4@example.com", Page: 1, PageSize: 10})
+		if err != nil || total != 1 || len(byEmail) != 1 || byEmail[0].Username != "user4" {
+			t.Fatalf("List by email: got %d users, total %d, %v", len(byEmail), total, err)
+		}
+
+		byUsername, total, err := repo.List(ctx, &model.UserListReq{Username: "user", Page: 1, PageSize: 10})
+		if err != nil || total != 0 || len(byUsername) != 0 {
+			t.Fatalf("List by username must match exactly: got %d users, total %d, %v", len(byUsername), total, err)
+		}
+
+		wildcard, total, err := repo.List(ctx, &model.UserListReq{Keyword: "%", Page: 1, PageSize: 10})
+		if err != nil || total != 0 || len(wildcard) != 0 {
+			t.Fatalf("List with wildcard keyword: got %d users, total %d, %v", len(wildcard), total, err)
+		}
+	})
+}
+
+// newUser 创建用例使用的用户
+func newUser(username string) *model.User {
+	return &model.User{
+		CasdoorId:   "casdoor-" + username,
+		Username:    username,
+		Email:       username + "@example.com",
+		DisplayName: username,
+		Status:      model.UserStatusActive,
+	}
+}
+
+// mustCreate 创建用户，失败时终止用例
+func mustCreate(t *testing.T, repo dao.UserRepository, user *model.User) *model.User {
+	t.Helper()
+	if err := repo.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create %s: %v", user.Username, err)
+	}
+	return user
+}
+
+// assertUser 比较用户的业务字段
+func assertUser(t *testing.T, name string, got, want *model.User) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("%s: user not found", name)
+	}
+	if got.Id != want.Id || got.CasdoorId != want.CasdoorId || got.Username != want.Username ||
+		got.Email != want.Email || got.DisplayName != want.DisplayName || got.Status != want.Status {
+		t.Fatalf("%s: got %+v, want %+v", name, got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type DataExportDao struct{}
+
+var DataExport = &DataExportDao{}
+
+// Create 创建导出记录，并回填自增ID
+func (d *DataExportDao) Create(ctx context.Context, export *model.DataExport) error {
+	id, err := g.DB().Model("data_exports").FieldsEx("id").Data(export).InsertAndGetId()
+	if err != nil {
+		return err
+	}
+	export.Id = uint64(id)
+	return nil
+}
+
+// Complete 保存生成的导出包并设置下载链接的过期时间
+func (d *DataExportDao) Complete(ctx context.Context, id uint64, archive []byte, expiresAt *gtime.Time) error {
+	_, err := g.DB().Model("data_exports").
+		Data(g.Map{
+			"status":       model.DataExportReady,
+			"archive":      archive,
+			"size":         len(archive),
+			"completed_at": gtime.Now(),
+			"expires_at":   expiresAt,
+		}).
+		Where("id", id).
+		Update()
+	return err
+}
+
+// Fail 记录生成失败的原因，失败记录在expiresAt之后清理
+func (d *DataExportDao) Fail(ctx context.Context, id uint64, reason string, expiresAt *gtime.Time) error {
+	_, err := g.DB().Model("data_exports").
+		Data(g.Map{
+			"status":       model.DataExportFailed,
+			"error":        reason,
+			"completed_at": gtime.Now(),
+			"expires_at":   expiresAt,
+		}).
+		Where("id", id).
+		Update()
+	return err
+}
+
+// GetByUser 获取用户的导出记录（不含导出包内容）
+func (d *DataExportDao) GetByUser(ctx context.Context, userId, id uint64) (*model.DataExport, error) {
+	var export *model.DataExport
+	err := g.DB().Model("data_exports").
+		FieldsEx("archive").
+		Where("id", id).
+		Where("user_id", userId).
+		Scan(&export)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// GetByHash 根据下载令牌摘要获取导出记录（含导出包内容）
+func (d *DataExportDao) GetByHash(ctx context.Context, id uint64, tokenHash string) (*model.DataExport, error) {
+	var export *model.DataExport
+	err := g.DB().Model("data_exports").
+		Where("id", id).
+		Where("token_hash", tokenHash).
+		Scan(&export)
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// HasPending 用户是否有正在生成的导出
+func (d *DataExportDao) HasPending(ctx context.Context, userId uint64) (bool, error) {
+	count, err := g.DB().Model("data_exports").
+		Where("user_id", userId).
+		Where("status", model.DataExportPending).
+		Count()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteByUser 删除用户的全部导出记录
+func (d *DataExportDao) DeleteByUser(ctx context.Context, userId uint64) error {
+	_, err := g.DB().Model("data_exports").Where("user_id", userId).Delete()
+	return err
+}
+
+// DeleteExpired 删除下载链接已过期的导出，以及创建时间早于staleBefore仍未完成的导出（生成进程已退出）
+func (d *DataExportDao) DeleteExpired(ctx context.Context, staleBefore *gtime.Time) (int64, error) {
+	result, err := g.DB().Model("data_exports").
+		Where("expires_at < ? OR (status = ? AND created_at < ?)", gtime.Now(), model.DataExportPending, staleBefore).
+		Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type DeviceAuthorizationDao struct{}
+
+var DeviceAuthorization = &DeviceAuthorizationDao{}
+
+// Create 创建设备授权记录
+func (d *DeviceAuthorizationDao) Create(ctx context.Context, authorization *model.DeviceAuthorization) error {
+	_, err := g.DB().Model("device_authorizations").FieldsEx("id").Data(authorization).Insert()
+	return err
+}
+
+// GetByDeviceCodeHash 根据device_code摘要获取设备授权记录
+func (d *DeviceAuthorizationDao) GetByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*model.DeviceAuthorization, error) {
+	var authorization *model.DeviceAuthorization
+	err := g.DB().Model("device_authorizations").Where("device_code_hash", deviceCodeHash).Scan(&authorization)
+	if err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+// GetByUserCode 根据用户码获取设备授权记录
+func (d *DeviceAuthorizationDao) GetByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	var authorization *model.DeviceAuthorization
+	err := g.DB().Model("device_authorizations").Where("user_code", userCode).Scan(&authorization)
+	if err != nil {
+		return nil, err
+	}
+	return authorization, nil
+}
+
+// Approve 用户登录完成，保存令牌；只有待授权的记录可以被批准
+func (d *DeviceAuthorizationDao) Approve(ctx context.Context, userCode, username, accessToken, refreshToken string, tokenExpiresAt *gtime.Time) (bool, error) {
+	result, err := g.DB().Model("device_authorizations").
+		Data(g.Map{
+			"status":           model.DeviceStatusApproved,
+			"username":         username,
+			"access_token":     accessToken,
+			"refresh_token":    refreshToken,
+			"token_expires_at": tokenExpiresAt,
+		}).
+		Where("user_code", userCode).
+		Where("status", model.DeviceStatusPending).
+		WhereGT("expires_at", gtime.Now()).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// Deny 用户拒绝授权
+func (d *DeviceAuthorizationDao) Deny(ctx context.Context, userCode string) error {
+	_, err := g.DB().Model("device_authorizations").
+		Data(g.Map{"status": model.DeviceStatusDenied}).
+		Where("user_code", userCode).
+		Where("status", model.DeviceStatusPending).
+		Update()
+	return err
+}
+
+// UpdatePoll 记录轮询时间和轮询间隔
+func (d *DeviceAuthorizationDao) UpdatePoll(ctx context.Context, id uint64, pollInterval int) error {
+	_, err := g.DB().Model("device_authorizations").
+		Data(g.Map{"last_polled_at": gtime.Now(), "poll_interval": pollInterval}).
+		Where("id", id).
+		Update()
+	return err
+}
+
+// Delete 删除设备授权记录，返回是否删除成功（保证令牌只被取走一次）
+func (d *DeviceAuthorizationDao) Delete(ctx context.Context, id uint64) (bool, error) {
+	result, err := g.DB().Model("device_authorizations").Where("id", id).Delete()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// DeleteExpired 清理过期的设备授权记录
+func (d *DeviceAuthorizationDao) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := g.DB().Model("device_authorizations").WhereLT("expires_at", gtime.Now()).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type OAuthStateDao struct{}
+
+var OAuthState = &OAuthStateDao{}
+
+// Create 保存state
+func (d *OAuthStateDao) Create(ctx context.Context, state *model.OAuthState) error {
+	_, err := g.DB().Model("oauth_states").Data(state).Insert()
+	return err
+}
+
+// Consume 原子地删除并返回state（DELETE ... RETURNING保证只有一个请求能取到）
+func (d *OAuthStateDao) Consume(ctx context.Context, state string) (*model.OAuthState, error) {
+	record, err := g.DB().GetOne(ctx,
+		"DELETE FROM oauth_states WHERE state = ? RETURNING state, data, expires_at, created_at",
+		state,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if record.IsEmpty() {
+		return nil, nil
+	}
+
+	var oauthState *model.OAuthState
+	if err := record.Struct(&oauthState); err != nil {
+		return nil, err
+	}
+	return oauthState, nil
+}
+
+// CountActive 统计未过期的state数量
+func (d *OAuthStateDao) CountActive(ctx context.Context) (int, error) {
+	return g.DB().Model("oauth_states").WhereGT("expires_at", gtime.Now()).Count()
+}
+
+// DeleteExpired 清理过期的state
+func (d *OAuthStateDao) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := g.DB().Model("oauth_states").WhereLT("expires_at", gtime.Now()).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,80 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type PersonalAccessTokenDao struct{}
+
+var PersonalAccessToken = &PersonalAccessTokenDao{}
+
+// Create 创建个人访问令牌，并回填自增ID
+func (d *PersonalAccessTokenDao) Create(ctx context.Context, token *model.PersonalAccessToken) error {
+	id, err := g.DB().Model("personal_access_tokens").FieldsEx("id").Data(token).InsertAndGetId()
+	if err != nil {
+		return err
+	}
+	token.Id = uint64(id)
+	return nil
+}
+
+// GetByHash 根据令牌摘要获取个人访问令牌
+func (d *PersonalAccessTokenDao) GetByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	var token *model.PersonalAccessToken
+	err := g.DB().Model("personal_access_tokens").Where("token_hash", tokenHash).Scan(&token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ListByUser 获取用户的所有个人访问令牌（包括已吊销和已过期的）
+func (d *PersonalAccessTokenDao) ListByUser(ctx context.Context, userId uint64) ([]*model.PersonalAccessToken, error) {
+	var tokens []*model.PersonalAccessToken
+	err := g.DB().Model("personal_access_tokens").Where("user_id", userId).OrderDesc("id").Scan(&tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke 吊销用户的个人访问令牌，返回是否吊销成功（不存在或已吊销返回false）
+func (d *PersonalAccessTokenDao) Revoke(ctx context.Context, userId, id uint64) (bool, error) {
+	result, err := g.DB().Model("personal_access_tokens").
+		Data(g.Map{"revoked_at": gtime.Now()}).
+		Where("id", id).
+		Where("user_id", userId).
+		WhereNull("revoked_at").
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RevokeAllByUser 吊销用户全部未吊销的个人访问令牌
+func (d *PersonalAccessTokenDao) RevokeAllByUser(ctx context.Context, userId uint64) error {
+	_, err := g.DB().Model("personal_access_tokens").
+		Data(g.Map{"revoked_at": gtime.Now()}).
+		Where("user_id", userId).
+		WhereNull("revoked_at").
+		Update()
+	return err
+}
+
+// TouchLastUsed 记录令牌最近一次使用的时间和IP
+func (d *PersonalAccessTokenDao) TouchLastUsed(ctx context.Context, id uint64, ip string) error {
+	_, err := g.DB().Model("personal_access_tokens").
+		Data(g.Map{"last_used_at": gtime.Now(), "last_used_ip": ip}).
+		Where("id", id).
+		Update()
+	return err
+}
@@ -0,0 +1,223 @@
+package dao
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ErrRedisNil Redis返回空值（键不存在）
+var ErrRedisNil = errors.New("redis: nil")
+
+// RedisDao 轻量Redis客户端（RESP2协议），读取配置文件中的redis.default配置
+type RedisDao struct {
+	once    sync.Once
+	initErr error
+	address string
+	pass    string
+	db      int
+	pool    chan *redisConn
+}
+
+var Redis = &RedisDao{}
+
+// redisConn 单个Redis连接
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// init 延迟加载Redis配置
+func (d *RedisDao) init(ctx context.Context) error {
+	d.once.Do(func() {
+		address, err := g.Cfg().Get(ctx, "redis.default.address")
+		if err != nil || address.IsEmpty() {
+			d.initErr = fmt.Errorf("redis.default.address 未配置")
+			return
+		}
+		d.address = address.String()
+		if pass, err := g.Cfg().Get(ctx, "redis.default.pass"); err == nil && !pass.IsEmpty() {
+			d.pass = pass.String()
+		}
+		if db, err := g.Cfg().Get(ctx, "redis.default.db"); err == nil && !db.IsEmpty() {
+			d.db = db.Int()
+		}
+		d.pool = make(chan *redisConn, 16)
+	})
+	return d.initErr
+}
+
+// Do 执行Redis命令，返回值为string、int64、[]interface{}或nil
+func (d *RedisDao) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	if err := d.init(ctx); err != nil {
+		return nil, err
+	}
+
+	conn, err := d.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(args...)
+	if err != nil {
+		// 协议错误或网络错误时丢弃连接，Redis返回的错误不影响连接复用
+		var replyErr redisError
+		if !errors.As(err, &replyErr) {
+			conn.conn.Close()
+			return nil, err
+		}
+	}
+	d.put(conn)
+	return reply, err
+}
+
+// get 从连接池获取连接，池中没有空闲连接时新建
+func (d *RedisDao) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-d.pool:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	netConn, err := dialer.DialContext(ctx, "tcp", d.address)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if d.pass != "" {
+		if _, err := conn.do("AUTH", d.pass); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if d.db != 0 {
+		if _, err := conn.do("SELECT", d.db); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// put 归还连接，连接池已满时关闭连接
+func (d *RedisDao) put(conn *redisConn) {
+	select {
+	case d.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+// redisError Redis服务端返回的错误
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// do 发送命令并读取响应
+func (c *redisConn) do(args ...interface{}) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return nil, err
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		value := fmt.Sprint(arg)
+		buf = append(buf, "$"+strconv.Itoa(len(value))+"\r\n"+value+"\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read 读取一个RESP响应
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("redis: invalid reply %q", line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// GetDel 原子地获取并删除键，键不存在时返回ErrRedisNil
+func (d *RedisDao) GetDel(ctx context.Context, key string) (string, error) {
+	reply, err := d.Do(ctx, "GETDEL", key)
+	if err != nil {
+		return "", err
+	}
+	if reply == nil {
+		return "", ErrRedisNil
+	}
+	return fmt.Sprint(reply), nil
+}
+
+// Eval 执行Lua脚本（脚本内的操作在Redis中原子执行）
+func (d *RedisDao) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	command := make([]interface{}, 0, 3+len(keys)+len(args))
+	command = append(command, "EVAL", script, len(keys))
+	for _, key := range keys {
+		command = append(command, key)
+	}
+	command = append(command, args...)
+	return d.Do(ctx, command...)
+}
+
+// SetEX 设置键值及过期时间（秒）
+func (d *RedisDao) SetEX(ctx context.Context, key, value string, ttlSeconds int64) error {
+	_, err := d.Do(ctx, "SET", key, value, "EX", ttlSeconds)
+	return err
+}
@@ -0,0 +1,92 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type RefreshTokenDao struct{}
+
+var RefreshToken = &RefreshTokenDao{}
+
+// Create 创建刷新令牌记录
+func (d *RefreshTokenDao) Create(ctx context.Context, token *model.RefreshToken) error {
+	_, err := g.DB().Model("refresh_tokens").FieldsEx("id").Data(token).Insert()
+	return err
+}
+
+// GetByHash 根据令牌摘要获取刷新令牌
+func (d *RefreshTokenDao) GetByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var token *model.RefreshToken
+	err := g.DB().Model("refresh_tokens").Where("token_hash", tokenHash).Scan(&token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Rotate 在事务中将刷新令牌标记为已使用并保存轮换后的新令牌，返回是否轮换成功（已使用或已吊销的令牌返回false，不保存新令牌）
+// link在同一事务中执行（例如将新的访问令牌关联到会话），返回错误时整个轮换回滚
+func (d *RefreshTokenDao) Rotate(ctx context.Context, id uint64, next *model.RefreshToken, link func(ctx context.Context) error) (bool, error) {
+	rotated := false
+	err := g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		result, err := tx.Model("refresh_tokens").
+			Data(g.Map{"used_at": gtime.Now()}).
+			Where("id", id).
+			WhereNull("used_at").
+			WhereNull("revoked_at").
+			Update()
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil || affected != 1 {
+			return err
+		}
+		if _, err := tx.Model("refresh_tokens").FieldsEx("id").Data(next).Insert(); err != nil {
+			return err
+		}
+		if link != nil {
+			if err := link(ctx); err != nil {
+				return err
+			}
+		}
+		rotated = true
+		return nil
+	})
+	return rotated, err
+}
+
+// RevokeFamily 吊销同一family下的所有刷新令牌
+func (d *RefreshTokenDao) RevokeFamily(ctx context.Context, familyId string) error {
+	_, err := g.DB().Model("refresh_tokens").
+		Data(g.Map{"revoked_at": gtime.Now()}).
+		Where("family_id", familyId).
+		WhereNull("revoked_at").
+		Update()
+	return err
+}
+
+// RevokeByUsername 吊销用户的所有刷新令牌
+func (d *RefreshTokenDao) RevokeByUsername(ctx context.Context, username string) error {
+	_, err := g.DB().Model("refresh_tokens").
+		Data(g.Map{"revoked_at": gtime.Now()}).
+		Where("username", username).
+		WhereNull("revoked_at").
+		Update()
+	return err
+}
+
+// ListByUsername 获取用户的全部刷新令牌（最近的在前）
+func (d *RefreshTokenDao) ListByUsername(ctx context.Context, username string) ([]*model.RefreshToken, error) {
+	var tokens []*model.RefreshToken
+	err := g.DB().Model("refresh_tokens").Where("username", username).OrderDesc("id").Scan(&tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type RevokedTokenDao struct{}
+
+var RevokedToken = &RevokedTokenDao{}
+
+// Create 记录被吊销的令牌（重复吊销时忽略）
+func (d *RevokedTokenDao) Create(ctx context.Context, token *model.RevokedToken) error {
+	_, err := g.DB().Model("revoked_tokens").Data(token).InsertIgnore()
+	return err
+}
+
+// Exists 判断jti是否已被吊销
+func (d *RevokedTokenDao) Exists(ctx context.Context, jti string) (bool, error) {
+	count, err := g.DB().Model("revoked_tokens").Where("jti", jti).Count()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired 清理已过期的吊销记录（令牌本身已失效，无需继续保留）
+func (d *RevokedTokenDao) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := g.DB().Model("revoked_tokens").WhereLT("expires_at", gtime.Now()).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SaveUserRevocation 记录用户级吊销时间（已存在则更新）
+func (d *RevokedTokenDao) SaveUserRevocation(ctx context.Context, revocation *model.UserTokenRevocation) error {
+	_, err := g.DB().Model("user_token_revocations").Data(revocation).OnConflict("username").Save()
+	return err
+}
+
+// GetUserRevocation 获取用户级吊销记录
+func (d *RevokedTokenDao) GetUserRevocation(ctx context.Context, username string) (*model.UserTokenRevocation, error) {
+	var revocation *model.UserTokenRevocation
+	err := g.DB().Model("user_token_revocations").Where("username", username).Scan(&revocation)
+	if err != nil {
+		return nil, err
+	}
+	return revocation, nil
+}
@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// schemaMigrationLock 迁移使用的PostgreSQL advisory lock键，保证多副本同时启动时迁移串行执行
+const schemaMigrationLock = 7281054
+
+type SchemaMigrationDao struct{}
+
+var SchemaMigration = &SchemaMigrationDao{}
+
+// EnsureTable 创建迁移记录表
+func (d *SchemaMigrationDao) EnsureTable(ctx context.Context) error {
+	_, err := g.DB().Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`)
+	return err
+}
+
+// List 获取已应用的迁移（按版本号升序）
+func (d *SchemaMigrationDao) List(ctx context.Context) ([]*model.SchemaMigration, error) {
+	var migrations []*model.SchemaMigration
+	err := g.DB().Model("schema_migrations").OrderAsc("version").Scan(&migrations)
+	if err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// Apply 在事务中执行迁移脚本并记录版本，版本已应用时返回false（被其他实例抢先执行）
+func (d *SchemaMigrationDao) Apply(ctx context.Context, version int64, name, script string) (bool, error) {
+	applied := false
+	err := g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		exists, err := d.lock(ctx, tx, version)
+		if err != nil || exists {
+			return err
+		}
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+		_, err = tx.Model("schema_migrations").Data(g.Map{
+			"version":    version,
+			"name":       name,
+			"applied_at": gtime.Now(),
+		}).Insert()
+		applied = err == nil
+		return err
+	})
+	return applied, err
+}
+
+// Revert 在事务中执行回滚脚本并删除版本记录，版本未应用时返回false
+func (d *SchemaMigrationDao) Revert(ctx context.Context, version int64, script string) (bool, error) {
+	reverted := false
+	err := g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		exists, err := d.lock(ctx, tx, version)
+		if err != nil || !exists {
+			return err
+		}
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+		_, err = tx.Model("schema_migrations").Where("version", version).Delete()
+		reverted = err == nil
+		return err
+	})
+	return reverted, err
+}
+
+// lock 获取事务级迁移锁，并返回版本是否已应用（加锁后读取，结果不受并发迁移影响）
+func (d *SchemaMigrationDao) lock(ctx context.Context, tx gdb.TX, version int64) (bool, error) {
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(?)", schemaMigrationLock); err != nil {
+		return false, err
+	}
+	count, err := tx.Model("schema_migrations").Where("version", version).Count()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
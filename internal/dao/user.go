@@ -3,14 +3,48 @@ package dao
 import (
 	"context"
 	"context-id-backend/internal/model"
+	"strings"
 
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
 )
 
+// UserRepository 本地用户存储；查询不到用户时返回nil和nil错误
+// 用户名、邮箱、Casdoor用户ID（非空时）在未删除的用户中唯一，Create/Update违反唯一约束时返回错误
+// 已软删除的用户不会被查询、更新或计入唯一约束，只能通过ListToAnonymize和Anonymize访问
+type UserRepository interface {
+	GetById(ctx context.Context, id uint64) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetByCasdoorId(ctx context.Context, casdoorId string) (*model.User, error)
+	// Create 创建用户，并回填自增ID
+	Create(ctx context.Context, user *model.User) error
+	// Update 按ID更新用户资料；状态相关字段只能通过UpdateStatus修改
+	// Casdoor用户ID为空表示未关联：Create时存为NULL，Update时保留原值，GetByCasdoorId("")查询不到用户
+	Update(ctx context.Context, user *model.User) error
+	// UpdateStatus 仅当用户当前状态为from时将其变更为to，并记录原因和变更时间
+	// 用户不存在或状态已被并发修改时返回false
+	UpdateStatus(ctx context.Context, id uint64, from, to int, reason string) (bool, error)
+	// List 按条件分页查询用户（默认按ID升序），返回当前页和总数
+	List(ctx context.Context, req *model.UserListReq) ([]*model.User, int, error)
+	// ListAll 获取全部用户（按ID升序）
+	ListAll(ctx context.Context) ([]*model.User, error)
+	// SoftDelete 软删除用户，用户不存在或已删除时返回false
+	SoftDelete(ctx context.Context, id uint64) (bool, error)
+	// ListToAnonymize 获取删除时间早于before且尚未匿名化的用户（按ID升序，最多limit个）
+	ListToAnonymize(ctx context.Context, before *gtime.Time, limit int) ([]*model.User, error)
+	// Anonymize 清除已删除用户的个人信息（邮箱、手机号、显示名称、头像），并记录匿名化时间
+	Anonymize(ctx context.Context, id uint64) error
+}
+
+// UserDao 基于PostgreSQL的用户存储
+// users表包含deleted_at列，GoFrame的查询和更新会自动排除已软删除的行，需要访问时使用Unscoped
 type UserDao struct{}
 
 var User = &UserDao{}
 
+var _ UserRepository = (*UserDao)(nil)
+
 // GetByUsername 根据用户名获取用户
 func (d *UserDao) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user *model.User
@@ -31,15 +65,169 @@ func (d *UserDao) GetByEmail(ctx context.Context, email string) (*model.User, er
 	return user, nil
 }
 
-// Create 创建用户
+// GetByCasdoorId 根据Casdoor用户ID获取用户
+func (d *UserDao) GetByCasdoorId(ctx context.Context, casdoorId string) (*model.User, error) {
+	var user *model.User
+	err := g.DB().Model("users").Where("casdoor_id", casdoorId).Scan(&user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// List 按条件分页查询用户，关键字匹配用户名、邮箱和显示名称
+func (d *UserDao) List(ctx context.Context, req *model.UserListReq) ([]*model.User, int, error) {
+	query := g.DB().Model("users")
+	if req.Keyword != "" {
+		pattern := "%" + escapeLike(req.Keyword) + "%"
+		query = query.Where("(username ILIKE ? OR email ILIKE ? OR display_name ILIKE ?)", pattern, pattern, pattern)
+	}
+	if req.Username != "" {
+		query = query.Where("username", req.Username)
+	}
+	if req.Email != "" {
+		query = query.Where("email", req.Email)
+	}
+	if req.Status != nil {
+		query = query.Where("status", *req.Status)
+	}
+
+	column, desc := userSortColumn(req.Sort)
+	if desc {
+		query = query.OrderDesc(column)
+	} else {
+		query = query.OrderAsc(column)
+	}
+	// 排序字段相同时按ID排序，保证分页结果稳定
+	if column != "id" {
+		query = query.OrderAsc("id")
+	}
+
+	var (
+		users []*model.User
+		total int
+	)
+	err := query.Page(req.Page, req.PageSize).ScanAndCount(&users, &total, false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// userSortColumn 解析排序参数（前缀-表示降序），不支持的字段按ID排序
+func userSortColumn(sort string) (string, bool) {
+	desc := strings.HasPrefix(sort, "-")
+	column := strings.TrimPrefix(sort, "-")
+	switch column {
+	case "id", "username", "email", "created_at", "updated_at":
+		return column, desc
+	default:
+		return "id", false
+	}
+}
+
+// escapeLike 转义LIKE模式中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// ListAll 获取全部本地用户（按ID排序）
+func (d *UserDao) ListAll(ctx context.Context) ([]*model.User, error) {
+	var users []*model.User
+	err := g.DB().Model("users").OrderAsc("id").Scan(&users)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Create 创建用户，并回填自增ID
 func (d *UserDao) Create(ctx context.Context, user *model.User) error {
-	_, err := g.DB().Model("users").Data(user).Insert()
-	return err
+	id, err := g.DB().Model("users").Data(user).FieldsEx("id").InsertAndGetId()
+	if err != nil {
+		return err
+	}
+	user.Id = uint64(id)
+	return nil
 }
 
 // Update 更新用户
 func (d *UserDao) Update(ctx context.Context, user *model.User) error {
-	_, err := g.DB().Model("users").Data(user).Where("id", user.Id).Update()
+	_, err := g.DB().Model("users").
+		Data(user).
+		FieldsEx("id", "status", "status_reason", "status_changed_at", "created_at", "deleted_at", "anonymized_at").
+		Where("id", user.Id).
+		Update()
+	return err
+}
+
+// UpdateStatus 按当前状态条件更新用户状态，避免并发变更互相覆盖
+func (d *UserDao) UpdateStatus(ctx context.Context, id uint64, from, to int, reason string) (bool, error) {
+	now := gtime.Now()
+	result, err := g.DB().Model("users").
+		Data(g.Map{"status": to, "status_reason": reason, "status_changed_at": now, "updated_at": now}).
+		Where("id", id).
+		Where("status", from).
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// SoftDelete 软删除用户（显式更新deleted_at，不依赖GoFrame的自动软删除，避免未迁移时误删数据）
+func (d *UserDao) SoftDelete(ctx context.Context, id uint64) (bool, error) {
+	result, err := g.DB().Model("users").
+		Data(g.Map{"deleted_at": gtime.Now()}).
+		Where("id", id).
+		WhereNull("deleted_at").
+		Update()
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ListToAnonymize 获取待匿名化的已删除用户
+func (d *UserDao) ListToAnonymize(ctx context.Context, before *gtime.Time, limit int) ([]*model.User, error) {
+	var users []*model.User
+	err := g.DB().Model("users").
+		Unscoped().
+		WhereLT("deleted_at", before).
+		WhereNull("anonymized_at").
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&users)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Anonymize 清除已删除用户的个人信息
+func (d *UserDao) Anonymize(ctx context.Context, id uint64) error {
+	now := gtime.Now()
+	_, err := g.DB().Model("users").
+		Unscoped().
+		Data(g.Map{
+			"email":         "",
+			"phone":         "",
+			"display_name":  "",
+			"avatar":        "",
+			"anonymized_at": now,
+			"updated_at":    now,
+		}).
+		Where("id", id).
+		WhereNotNull("deleted_at").
+		Update()
 	return err
 }
 
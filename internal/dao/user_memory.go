@@ -0,0 +1,288 @@
+package dao
+
+import (
+	"cmp"
+	"context"
+	"context-id-backend/internal/model"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// ErrUserConflict 用户名、邮箱或Casdoor用户ID已被其他用户使用
+var ErrUserConflict = errors.New("user already exists")
+
+// MemoryUserRepository 进程内的用户存储（并发安全），用于测试和无数据库的本地运行
+// 与数据库实现保持相同的唯一约束和软删除行为；存取时复制用户，调用方修改返回值不会影响已保存的数据
+type MemoryUserRepository struct {
+	users  map[uint64]*model.User
+	nextId uint64
+	mutex  sync.RWMutex
+}
+
+var _ UserRepository = (*MemoryUserRepository)(nil)
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:  make(map[uint64]*model.User),
+		nextId: 1,
+	}
+}
+
+func (m *MemoryUserRepository) GetById(ctx context.Context, id uint64) (*model.User, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	user := m.users[id]
+	if user == nil || user.DeletedAt != nil {
+		return nil, nil
+	}
+	return cloneUser(user), nil
+}
+
+func (m *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return m.find(func(user *model.User) bool { return user.Username == username }), nil
+}
+
+func (m *MemoryUserRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return m.find(func(user *model.User) bool { return user.Email == email }), nil
+}
+
+func (m *MemoryUserRepository) GetByCasdoorId(ctx context.Context, casdoorId string) (*model.User, error) {
+	// 与数据库实现一致：空ID存为NULL，不会匹配任何用户
+	if casdoorId == "" {
+		return nil, nil
+	}
+	return m.find(func(user *model.User) bool { return user.CasdoorId == casdoorId }), nil
+}
+
+func (m *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.checkUnique(user, 0); err != nil {
+		return err
+	}
+	user.Id = m.nextId
+	m.nextId++
+	m.users[user.Id] = cloneUser(user)
+	return nil
+}
+
+func (m *MemoryUserRepository) Update(ctx context.Context, user *model.User) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// 与数据库实现一致：用户不存在时不报错
+	existing, exists := m.users[user.Id]
+	if !exists || existing.DeletedAt != nil {
+		return nil
+	}
+	if err := m.checkUnique(user, user.Id); err != nil {
+		return err
+	}
+	updated := cloneUser(user)
+	if updated.CasdoorId == "" {
+		updated.CasdoorId = existing.CasdoorId
+	}
+	updated.Status = existing.Status
+	updated.StatusReason = existing.StatusReason
+	updated.StatusChangedAt = existing.StatusChangedAt
+	updated.CreatedAt = existing.CreatedAt
+	updated.DeletedAt = existing.DeletedAt
+	updated.AnonymizedAt = existing.AnonymizedAt
+	m.users[user.Id] = updated
+	return nil
+}
+
+func (m *MemoryUserRepository) UpdateStatus(ctx context.Context, id uint64, from, to int, reason string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	user, exists := m.users[id]
+	if !exists || user.DeletedAt != nil || user.Status != from {
+		return false, nil
+	}
+	now := gtime.Now()
+	user.Status = to
+	user.StatusReason = reason
+	user.StatusChangedAt = now
+	user.UpdatedAt = now
+	return true, nil
+}
+
+func (m *MemoryUserRepository) List(ctx context.Context, req *model.UserListReq) ([]*model.User, int, error) {
+	keyword := strings.ToLower(req.Keyword)
+	matched := m.filter(func(user *model.User) bool {
+		if req.Status != nil && user.Status != *req.Status {
+			return false
+		}
+		if req.Username != "" && user.Username != req.Username {
+			return false
+		}
+		if req.Email != "" && user.Email != req.Email {
+			return false
+		}
+		if keyword == "" {
+			return true
+		}
+		return strings.Contains(strings.ToLower(user.Username), keyword) ||
+			strings.Contains(strings.ToLower(user.Email), keyword) ||
+			strings.Contains(strings.ToLower(user.DisplayName), keyword)
+	})
+
+	column, desc := userSortColumn(req.Sort)
+	sort.SliceStable(matched, func(i, j int) bool {
+		result := compareUsers(matched[i], matched[j], column)
+		if desc {
+			return result > 0
+		}
+		return result < 0
+	})
+
+	total := len(matched)
+	start := (req.Page - 1) * req.PageSize
+	if start < 0 || start >= total {
+		return []*model.User{}, total, nil
+	}
+	end := start + req.PageSize
+	if end > total {
+		end = total
+	}
+	return matched[start:end], total, nil
+}
+
+func (m *MemoryUserRepository) ListAll(ctx context.Context) ([]*model.User, error) {
+	return m.filter(func(user *model.User) bool { return true }), nil
+}
+
+func (m *MemoryUserRepository) SoftDelete(ctx context.Context, id uint64) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	user, exists := m.users[id]
+	if !exists || user.DeletedAt != nil {
+		return false, nil
+	}
+	user.DeletedAt = gtime.Now()
+	return true, nil
+}
+
+func (m *MemoryUserRepository) ListToAnonymize(ctx context.Context, before *gtime.Time, limit int) ([]*model.User, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	users := make([]*model.User, 0)
+	for _, user := range m.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(before) && user.AnonymizedAt == nil {
+			users = append(users, cloneUser(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *MemoryUserRepository) Anonymize(ctx context.Context, id uint64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	user, exists := m.users[id]
+	if !exists || user.DeletedAt == nil {
+		return nil
+	}
+	now := gtime.Now()
+	user.Email = ""
+	user.Phone = ""
+	user.DisplayName = ""
+	user.Avatar = ""
+	user.AnonymizedAt = now
+	user.UpdatedAt = now
+	return nil
+}
+
+// find 返回第一个满足条件的用户（按ID升序）
+func (m *MemoryUserRepository) find(match func(user *model.User) bool) *model.User {
+	users := m.filter(match)
+	if len(users) == 0 {
+		return nil
+	}
+	return users[0]
+}
+
+// filter 返回满足条件的未删除用户副本（按ID升序）
+func (m *MemoryUserRepository) filter(match func(user *model.User) bool) []*model.User {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	users := make([]*model.User, 0)
+	for _, user := range m.users {
+		if user.DeletedAt == nil && match(user) {
+			users = append(users, cloneUser(user))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Id < users[j].Id })
+	return users
+}
+
+// checkUnique 检查未删除用户间的唯一约束，excludeId为正在更新的用户（调用方需持有锁）
+func (m *MemoryUserRepository) checkUnique(user *model.User, excludeId uint64) error {
+	for id, existing := range m.users {
+		if id == excludeId || existing.DeletedAt != nil {
+			continue
+		}
+		switch {
+		case existing.Username == user.Username:
+			return fmt.Errorf("%w: username %s", ErrUserConflict, user.Username)
+		case existing.Email == user.Email:
+			return fmt.Errorf("%w: email %s", ErrUserConflict, user.Email)
+		case user.CasdoorId != "" && existing.CasdoorId == user.CasdoorId:
+			return fmt.Errorf("%w: casdoor id %s", ErrUserConflict, user.CasdoorId)
+		}
+	}
+	return nil
+}
+
+// compareUsers 按排序字段比较两个用户
+func compareUsers(a, b *model.User, column string) int {
+	switch column {
+	case "username":
+		return strings.Compare(a.Username, b.Username)
+	case "email":
+		return strings.Compare(a.Email, b.Email)
+	case "created_at":
+		return compareTime(a.CreatedAt, b.CreatedAt)
+	case "updated_at":
+		return compareTime(a.UpdatedAt, b.UpdatedAt)
+	default:
+		return cmp.Compare(a.Id, b.Id)
+	}
+}
+
+// compareTime 比较时间，空值视为最大（与PostgreSQL的NULL排序一致）
+func compareTime(a, b *gtime.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Time.Compare(b.Time)
+}
+
+// cloneUser 复制用户（nil返回nil）
+func cloneUser(user *model.User) *model.User {
+	if user == nil {
+		return nil
+	}
+	clone := *user
+	return &clone
+}
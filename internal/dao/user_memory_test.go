@@ -0,0 +1,13 @@
+package dao_test
+
+import (
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/dao/daotest"
+	"testing"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	daotest.TestUserRepository(t, func(t *testing.T) dao.UserRepository {
+		return dao.NewMemoryUserRepository()
+	})
+}
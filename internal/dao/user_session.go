@@ -0,0 +1,165 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type UserSessionDao struct{}
+
+var UserSession = &UserSessionDao{}
+
+// Create 创建会话记录，并关联会话的访问令牌
+func (d *UserSessionDao) Create(ctx context.Context, session *model.UserSession) error {
+	return g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		id, err := tx.Model("user_sessions").FieldsEx("id").Data(session).InsertAndGetId()
+		if err != nil {
+			return err
+		}
+		session.Id = uint64(id)
+		return d.addToken(tx, session.Id, session.TokenId)
+	})
+}
+
+// GetById 根据ID获取会话
+func (d *UserSessionDao) GetById(ctx context.Context, id uint64) (*model.UserSession, error) {
+	var session *model.UserSession
+	err := g.DB().Model("user_sessions").Where("id", id).Scan(&session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// GetByTokenId 根据访问令牌jti获取会话（刷新前签发的访问令牌同样能找到所属会话）
+func (d *UserSessionDao) GetByTokenId(ctx context.Context, tokenId string) (*model.UserSession, error) {
+	var session *model.UserSession
+	err := g.DB().Model("user_sessions s").
+		InnerJoin("user_session_tokens t", "t.session_id = s.id").
+		Fields("s.*").
+		Where("t.token_id", tokenId).
+		Scan(&session)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ListByUser 获取用户的全部会话（含已结束的，最近的在前）
+func (d *UserSessionDao) ListByUser(ctx context.Context, userId uint64) ([]*model.UserSession, error) {
+	var sessions []*model.UserSession
+	err := g.DB().Model("user_sessions").Where("user_id", userId).OrderDesc("id").Scan(&sessions)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ListActiveByUser 获取用户未结束且未过期的会话
+func (d *UserSessionDao) ListActiveByUser(ctx context.Context, userId uint64) ([]*model.UserSession, error) {
+	var sessions []*model.UserSession
+	err := g.DB().Model("user_sessions").
+		Where("user_id", userId).
+		WhereNull("terminated_at").
+		WhereGT("expires_at", gtime.Now()).
+		OrderDesc("last_seen_at").
+		Scan(&sessions)
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Terminate 结束用户的指定会话，返回被结束的会话（不存在或已结束返回nil）
+func (d *UserSessionDao) Terminate(ctx context.Context, userId, id uint64) (*model.UserSession, error) {
+	record, err := g.DB().GetOne(ctx,
+		"UPDATE user_sessions SET terminated_at = ? WHERE id = ? AND user_id = ? AND terminated_at IS NULL RETURNING *",
+		gtime.Now(), id, userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if record.IsEmpty() {
+		return nil, nil
+	}
+
+	var session *model.UserSession
+	if err := record.Struct(&session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// TerminateByTokenId 结束访问令牌所属的会话
+func (d *UserSessionDao) TerminateByTokenId(ctx context.Context, tokenId string) error {
+	_, err := g.DB().Exec(ctx,
+		"UPDATE user_sessions SET terminated_at = ? WHERE terminated_at IS NULL AND id IN (SELECT session_id FROM user_session_tokens WHERE token_id = ?)",
+		gtime.Now(), tokenId,
+	)
+	return err
+}
+
+// TerminateByUsername 结束用户的所有会话
+func (d *UserSessionDao) TerminateByUsername(ctx context.Context, username string) error {
+	_, err := g.DB().Model("user_sessions").
+		Data(g.Map{"terminated_at": gtime.Now()}).
+		Where("username", username).
+		WhereNull("terminated_at").
+		Update()
+	return err
+}
+
+// UpdateTokenId 刷新令牌轮换后，将会话的当前访问令牌更新为新令牌，之前的访问令牌仍关联在会话上
+// 返回更新后的会话（family没有未结束的会话时返回nil）；ctx中带有事务时在该事务中执行
+func (d *UserSessionDao) UpdateTokenId(ctx context.Context, familyId, tokenId string) (*model.UserSession, error) {
+	var session *model.UserSession
+	err := g.DB().Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		record, err := tx.GetOne(
+			"UPDATE user_sessions SET token_id = ?, last_seen_at = ? WHERE family_id = ? AND terminated_at IS NULL RETURNING *",
+			tokenId, gtime.Now(), familyId,
+		)
+		if err != nil || record.IsEmpty() {
+			return err
+		}
+		if err := record.Struct(&session); err != nil {
+			return err
+		}
+		return d.addToken(tx, session.Id, tokenId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// addToken 将访问令牌关联到会话
+func (d *UserSessionDao) addToken(tx gdb.TX, sessionId uint64, tokenId string) error {
+	_, err := tx.Model("user_session_tokens").Data(g.Map{
+		"token_id":   tokenId,
+		"session_id": sessionId,
+		"created_at": gtime.Now(),
+	}).InsertIgnore()
+	return err
+}
+
+// TouchLastSeen 更新会话的最近活跃时间和IP
+func (d *UserSessionDao) TouchLastSeen(ctx context.Context, id uint64, ip string) error {
+	_, err := g.DB().Model("user_sessions").
+		Data(g.Map{"last_seen_at": gtime.Now(), "ip": ip}).
+		Where("id", id).
+		Update()
+	return err
+}
+
+// DeleteExpired 清理已过期的会话记录
+func (d *UserSessionDao) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := g.DB().Model("user_sessions").WhereLT("expires_at", gtime.Now()).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
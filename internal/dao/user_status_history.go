@@ -0,0 +1,32 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+type UserStatusHistoryDao struct{}
+
+var UserStatusHistory = &UserStatusHistoryDao{}
+
+// Create 记录一次状态变更，并回填自增ID
+func (d *UserStatusHistoryDao) Create(ctx context.Context, change *model.UserStatusChange) error {
+	id, err := g.DB().Model("user_status_history").FieldsEx("id").Data(change).InsertAndGetId()
+	if err != nil {
+		return err
+	}
+	change.Id = uint64(id)
+	return nil
+}
+
+// ListByUser 获取用户的状态变更记录（最近的在前）
+func (d *UserStatusHistoryDao) ListByUser(ctx context.Context, userId uint64) ([]*model.UserStatusChange, error) {
+	var changes []*model.UserStatusChange
+	err := g.DB().Model("user_status_history").Where("user_id", userId).OrderDesc("id").Scan(&changes)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,38 @@
+package dao_test
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/dao/daotest"
+	"context-id-backend/internal/service"
+	"os"
+	"testing"
+
+	_ "github.com/gogf/gf/contrib/drivers/pgsql/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// TestUserDao 对PostgreSQL实现运行契约测试，需设置TEST_DATABASE_LINK指向可清空的测试库，例如：
+// TEST_DATABASE_LINK="pgsql:postgres:postgres@tcp(127.0.0.1:5432)/contextid_test?sslmode=disable"
+func TestUserDao(t *testing.T) {
+	link := os.Getenv("TEST_DATABASE_LINK")
+	if link == "" {
+		t.Skip("TEST_DATABASE_LINK not set")
+	}
+
+	ctx := context.Background()
+	if err := gdb.SetConfig(gdb.Config{gdb.DefaultGroupName: gdb.ConfigGroup{{Link: link}}}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if _, err := service.Migrate.Up(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	daotest.TestUserRepository(t, func(t *testing.T) dao.UserRepository {
+		if _, err := g.DB().Exec(ctx, "TRUNCATE users RESTART IDENTITY CASCADE"); err != nil {
+			t.Fatalf("TRUNCATE users: %v", err)
+		}
+		return dao.User
+	})
+}
@@ -0,0 +1,54 @@
+package dao
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+type WebhookEventDao struct{}
+
+var WebhookEvent = &WebhookEventDao{}
+
+// Create 记录事件，事件ID已存在时返回false（重复投递）
+func (d *WebhookEventDao) Create(ctx context.Context, event *model.WebhookEvent) (bool, error) {
+	record, err := g.DB().GetOne(ctx,
+		`INSERT INTO webhook_events (event_id, source, action, username, status, received_at)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (event_id) DO NOTHING RETURNING id`,
+		event.EventId, event.Source, event.Action, event.Username, event.Status, event.ReceivedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	if record.IsEmpty() {
+		return false, nil
+	}
+	event.Id = record["id"].Uint64()
+	return true, nil
+}
+
+// UpdateStatus 更新事件的处理结果
+func (d *WebhookEventDao) UpdateStatus(ctx context.Context, id uint64, status string) error {
+	_, err := g.DB().Model("webhook_events").
+		Data(g.Map{"status": status}).
+		Where("id", id).
+		Update()
+	return err
+}
+
+// Delete 删除事件记录（处理失败时删除，允许发送方重试）
+func (d *WebhookEventDao) Delete(ctx context.Context, id uint64) error {
+	_, err := g.DB().Model("webhook_events").Where("id", id).Delete()
+	return err
+}
+
+// DeleteBefore 删除指定时间之前接收的事件，返回删除的数量
+func (d *WebhookEventDao) DeleteBefore(ctx context.Context, before *gtime.Time) (int64, error) {
+	result, err := g.DB().Model("webhook_events").WhereLT("received_at", before).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
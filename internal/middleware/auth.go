@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context-id-backend/internal/model"
 	"context-id-backend/internal/service"
 	"strings"
 
@@ -44,43 +45,125 @@ func Auth(r *ghttp.Request) {
 		}
 	}
 
-	// 获取Authorization header
+	// 优先使用Authorization头（API客户端），其次使用Cookie会话（启用bff时的浏览器）
+	token, ok := requestToken(r)
+	if !ok {
+		return
+	}
+
+	// 验证token
+	identity, err := service.Casdoor.Authenticate(ctx, token)
+	if err != nil {
+		g.Log().Error(ctx, "Token verification failed:", err)
+		writeTokenError(r, err)
+		return
+	}
+
+	// 已暂停、已删除或待验证的用户不能访问接口（令牌在状态变更前签发时仍可能有效）
+	if identity.User != nil {
+		if statusErr := service.AsUserStatusError(service.CheckUserStatus(identity.User)); statusErr != nil {
+			writeUserStatusError(r, identity.User, statusErr)
+			return
+		}
+	}
+
+	// 将用户信息和身份（角色/权限）存储到上下文中，供授权中间件和控制器使用
+	// 服务间调用没有用户，存储的是服务调用方（service principal）
+	if identity.IsService() {
+		g.Log().Info(ctx, "Service principal request:", identity.Service.Name, "client:", identity.Service.ClientId, r.Method, r.URL.Path)
+		r.SetCtxVar("service", identity.Service)
+	} else {
+		r.SetCtxVar("user", identity.User)
+	}
+	r.SetCtxVar("identity", identity)
+	r.Middleware.Next()
+}
+
+// requestToken 从Authorization头或Cookie会话中取出访问令牌，失败时已写入响应
+func requestToken(r *ghttp.Request) (string, bool) {
 	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
+	if authHeader != "" {
+		// 检查Bearer token格式
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			r.Response.Status = 401
+			r.Response.WriteJson(g.Map{
+				"code":    401,
+				"message": "认证格式错误",
+			})
+			return "", false
+		}
+		return parts[1], true
+	}
+
+	session, err := service.BFF.SessionFromRequest(r)
+	if err != nil {
+		g.Log().Warning(r.Context(), "Session cookie rejected:", err)
 		r.Response.Status = 401
 		r.Response.WriteJson(g.Map{
 			"code":    401,
-			"message": "未提供认证信息",
+			"message": "会话无效，请重新登录",
+			"error":   "session_invalid",
 		})
-		return
+		return "", false
 	}
-
-	// 检查Bearer token格式
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || parts[0] != "Bearer" {
+	if session == nil {
 		r.Response.Status = 401
 		r.Response.WriteJson(g.Map{
 			"code":    401,
-			"message": "认证格式错误",
+			"message": "未提供认证信息",
 		})
-		return
+		return "", false
 	}
 
-	token := parts[1]
-
-	// 验证token
-	user, err := service.Casdoor.VerifyToken(ctx, token)
-	if err != nil {
-		g.Log().Error(ctx, "Token verification failed:", err)
-		r.Response.Status = 401
+	// Cookie由浏览器自动携带，修改类请求必须通过CSRF校验
+	if !service.BFF.CheckCSRF(r, session) {
+		g.Log().Warning(r.Context(), "CSRF check failed:", r.Method, r.URL.Path)
+		r.Response.Status = 403
 		r.Response.WriteJson(g.Map{
-			"code":    401,
-			"message": "认证失败",
+			"code":    403,
+			"message": "CSRF校验失败",
+			"error":   "csrf_failed",
 		})
-		return
+		return "", false
 	}
+	return session.AccessToken, true
+}
 
-	// 将用户信息存储到上下文中
-	r.SetCtxVar("user", user)
-	r.Middleware.Next()
+// writeTokenError 返回401，并通过error字段告知客户端具体的校验失败原因
+func writeTokenError(r *ghttp.Request, err error) {
+	response := g.Map{
+		"code":    401,
+		"message": "认证失败",
+	}
+	reason := err.Error()
+	if tokenErr := service.AsTokenError(err); tokenErr != nil {
+		response["message"] = tokenErr.Message
+		response["error"] = tokenErr.Code
+		reason = tokenErr.Code
+	}
+	service.Audit.Record(r.Context(), &model.AuditEvent{
+		EventType: model.AuditEventTokenRejected,
+		Reason:    reason,
+		Detail:    g.Map{"method": r.Method, "path": r.URL.Path},
+	})
+	r.Response.Status = 401
+	r.Response.WriteJson(response)
+}
+
+// writeUserStatusError 返回403，并通过error字段告知客户端账号状态（如account_suspended）
+func writeUserStatusError(r *ghttp.Request, user *model.User, statusErr *service.UserStatusError) {
+	service.Audit.Record(r.Context(), &model.AuditEvent{
+		EventType: model.AuditEventTokenRejected,
+		UserId:    user.Id,
+		Username:  user.Username,
+		Reason:    statusErr.Code,
+		Detail:    g.Map{"method": r.Method, "path": r.URL.Path},
+	})
+	r.Response.Status = 403
+	r.Response.WriteJson(g.Map{
+		"code":    403,
+		"message": statusErr.Message,
+		"error":   statusErr.Code,
+	})
 }
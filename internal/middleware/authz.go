@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context-id-backend/internal/service"
+	"strings"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// CurrentIdentity 获取Auth中间件写入上下文的调用方身份，未认证时返回nil
+func CurrentIdentity(r *ghttp.Request) *service.Identity {
+	identity, _ := r.GetCtxVar("identity").Interface().(*service.Identity)
+	return identity
+}
+
+// RequireRoles 要求调用方拥有任意一个指定角色，需在Auth之后使用
+//
+//	group.Middleware(middleware.Auth, middleware.RequireRoles("admin"))
+func RequireRoles(roles ...string) ghttp.HandlerFunc {
+	return func(r *ghttp.Request) {
+		identity := CurrentIdentity(r)
+		if identity == nil {
+			writeUnauthenticated(r)
+			return
+		}
+
+		for _, role := range roles {
+			if identity.HasRole(role) {
+				r.Middleware.Next()
+				return
+			}
+		}
+
+		g.Log().Warning(r.Context(), "Role check failed, caller:", identity.Name(), "required:", roles)
+		writeForbidden(r, "需要角色: "+strings.Join(roles, ", "))
+	}
+}
+
+// RequireAdmin 要求调用方为管理员（Casdoor组织管理员或拥有auth.adminRoles中的角色），需在Auth之后使用
+func RequireAdmin(r *ghttp.Request) {
+	identity := CurrentIdentity(r)
+	if identity == nil {
+		writeUnauthenticated(r)
+		return
+	}
+	if !identity.IsAdministrator() {
+		g.Log().Warning(r.Context(), "Admin check failed, caller:", identity.Name())
+		writeForbidden(r, "需要管理员权限")
+		return
+	}
+	r.Middleware.Next()
+}
+
+// RequirePermissions 要求调用方拥有全部指定权限（取自token中的Casdoor权限），需在Auth之后使用
+func RequirePermissions(permissions ...string) ghttp.HandlerFunc {
+	return func(r *ghttp.Request) {
+		identity := CurrentIdentity(r)
+		if identity == nil {
+			writeUnauthenticated(r)
+			return
+		}
+
+		for _, permission := range permissions {
+			if !identity.HasPermission(permission) {
+				g.Log().Warning(r.Context(), "Permission check failed, caller:", identity.Name(), "missing:", permission)
+				writeForbidden(r, "缺少权限: "+permission)
+				return
+			}
+		}
+		r.Middleware.Next()
+	}
+}
+
+// RequireScopes 要求令牌具有全部指定权限范围，需在Auth之后使用
+// 个人访问令牌和服务令牌受权限范围限制，交互式登录会话不受限
+func RequireScopes(scopes ...string) ghttp.HandlerFunc {
+	return func(r *ghttp.Request) {
+		identity := CurrentIdentity(r)
+		if identity == nil {
+			writeUnauthenticated(r)
+			return
+		}
+
+		for _, scope := range scopes {
+			if !identity.HasScope(scope) {
+				g.Log().Warning(r.Context(), "Scope check failed, caller:", identity.Name(), "missing:", scope)
+				writeForbidden(r, "令牌缺少权限范围: "+scope)
+				return
+			}
+		}
+		r.Middleware.Next()
+	}
+}
+
+// RequireEnforce 通过Casdoor的权限模型（Enforce API）检查调用方能否对资源执行操作，需在Auth之后使用
+// permissionId格式为"owner/name"
+func RequireEnforce(permissionId, object, action string) ghttp.HandlerFunc {
+	return func(r *ghttp.Request) {
+		ctx := r.Context()
+
+		identity := CurrentIdentity(r)
+		if identity == nil {
+			writeUnauthenticated(r)
+			return
+		}
+
+		allowed, err := service.Casdoor.Enforce(ctx, identity, permissionId, object, action)
+		if err != nil {
+			r.Response.Status = 503
+			r.Response.WriteJson(g.Map{
+				"code":    503,
+				"message": "权限服务暂不可用",
+			})
+			return
+		}
+		if !allowed {
+			g.Log().Warning(ctx, "Enforce denied, caller:", identity.Name(), "object:", object, "action:", action)
+			writeForbidden(r, "无权执行"+action+"操作")
+			return
+		}
+		r.Middleware.Next()
+	}
+}
+
+// writeUnauthenticated 授权中间件前未经过Auth中间件时返回401
+func writeUnauthenticated(r *ghttp.Request) {
+	r.Response.Status = 401
+	r.Response.WriteJson(g.Map{
+		"code":    401,
+		"message": "未提供认证信息",
+	})
+}
+
+// writeForbidden 返回403，与认证失败的401区分
+func writeForbidden(r *ghttp.Request, reason string) {
+	r.Response.Status = 403
+	r.Response.WriteJson(g.Map{
+		"code":    403,
+		"message": "权限不足",
+		"error":   "forbidden",
+		"reason":  reason,
+	})
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// 审计事件类型
+const (
+	AuditEventLoginSuccess     = "login_success"     // 登录成功
+	AuditEventLoginFailure     = "login_failure"     // 登录失败
+	AuditEventStateRejected    = "state_rejected"    // OAuth state校验失败
+	AuditEventTokenRejected    = "token_rejected"    // 访问令牌被拒绝
+	AuditEventLogout           = "logout"            // 登出
+	AuditEventProfileUpdated   = "profile_updated"   // 用户资料变更（从Casdoor同步）
+	AuditEventUserDeactivated  = "user_deactivated"  // 用户变为已删除（在Casdoor中被删除）
+	AuditEventUserUpdated      = "user_updated"      // 管理员修改用户资料
+	AuditEventUserSuspended    = "user_suspended"    // 用户被暂停
+	AuditEventUserReactivated  = "user_reactivated"  // 被暂停的用户恢复正常
+	AuditEventUserActivated    = "user_activated"    // 待验证的用户完成验证
+	AuditEventAccountDeleted   = "account_deleted"   // 用户删除自己的账号
+	AuditEventUserAnonymized   = "user_anonymized"   // 已删除用户的个人信息被匿名化
+	AuditEventDataExported     = "data_exported"     // 用户导出个人数据
+	AuditEventExportDownloaded = "export_downloaded" // 通过下载链接下载异步生成的导出包
+)
+
+// AuditEvent 审计事件模型
+type AuditEvent struct {
+	Id        uint64                 `json:"id" db:"id"`
+	EventType string                 `json:"eventType" db:"event_type"`
+	UserId    uint64                 `json:"userId" db:"user_id"` // 无法确定用户时为0
+	Username  string                 `json:"username" db:"username"`
+	Success   bool                   `json:"success" db:"success"`
+	Reason    string                 `json:"reason" db:"reason"` // 失败原因
+	Ip        string                 `json:"ip" db:"ip"`
+	UserAgent string                 `json:"userAgent" db:"user_agent"`
+	RequestId string                 `json:"requestId" db:"request_id"`
+	Detail    map[string]interface{} `json:"detail" db:"detail"` // 事件相关的附加信息
+	CreatedAt *gtime.Time            `json:"createdAt" db:"created_at"`
+}
+
+// AuditEventListReq 审计事件查询请求
+type AuditEventListReq struct {
+	EventType string      `json:"event_type"`
+	Username  string      `json:"username"`
+	UserId    uint64      `json:"user_id"`
+	Success   *bool       `json:"success"`
+	Since     *gtime.Time `json:"since"` // 起始时间（包含）
+	Until     *gtime.Time `json:"until"` // 结束时间（不包含）
+	Page      int         `json:"page" d:"1" v:"min:1#页码必须大于0"`
+	PageSize  int         `json:"page_size" d:"20" v:"between:1,100#每页数量为1-100"`
+}
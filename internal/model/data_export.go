@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// 个人数据导出状态
+const (
+	DataExportPending = "pending" // 生成中
+	DataExportReady   = "ready"   // 已生成，可以下载
+	DataExportFailed  = "failed"  // 生成失败
+)
+
+// DataExport 异步生成的个人数据导出包，通过带令牌的链接下载（数据库只保存令牌摘要）
+type DataExport struct {
+	Id          uint64      `json:"id" db:"id"`
+	UserId      uint64      `json:"userId" db:"user_id"`
+	Status      string      `json:"status" db:"status"` // 见DataExport常量
+	TokenHash   string      `json:"-" db:"token_hash" orm:"token_hash"`
+	Archive     []byte      `json:"-" db:"archive" orm:"archive"`
+	Size        int64       `json:"size" db:"size"` // 导出包字节数
+	Error       string      `json:"error" db:"error"`
+	CreatedAt   *gtime.Time `json:"createdAt" db:"created_at"`
+	CompletedAt *gtime.Time `json:"completedAt" db:"completed_at"`
+	ExpiresAt   *gtime.Time `json:"expiresAt" db:"expires_at"` // 下载链接过期时间，生成完成后设置
+}
+
+// UserDataExport 导出包中data.json的内容：本服务保存的与用户相关的全部数据
+type UserDataExport struct {
+	ExportedAt           *gtime.Time            `json:"exportedAt"`
+	User                 *User                  `json:"user"`
+	StatusHistory        []*UserStatusChange    `json:"statusHistory"`
+	Sessions             []*UserSession         `json:"sessions"`
+	PersonalAccessTokens []*PersonalAccessToken `json:"personalAccessTokens"` // 只含元数据，不含令牌
+	RefreshTokens        []*RefreshToken        `json:"refreshTokens"`        // 只含元数据，不含令牌
+	AuditEvents          []*AuditEvent          `json:"auditEvents"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// 设备授权状态
+const (
+	DeviceStatusPending  = "pending"  // 等待用户在浏览器中完成登录
+	DeviceStatusApproved = "approved" // 用户已登录，等待CLI取走令牌
+	DeviceStatusDenied   = "denied"   // 用户拒绝授权或登录失败
+)
+
+// DeviceAuthorization 设备授权记录（RFC 8628，CLI登录使用）
+// 登录完成后令牌暂存在记录中，CLI轮询取走后立即删除
+type DeviceAuthorization struct {
+	Id             uint64      `json:"id" db:"id"`
+	DeviceCodeHash string      `json:"-" db:"device_code_hash" orm:"device_code_hash"`
+	UserCode       string      `json:"userCode" db:"user_code"`
+	Status         string      `json:"status" db:"status"`
+	Username       string      `json:"username" db:"username"`
+	AccessToken    string      `json:"-" db:"access_token" orm:"access_token"`
+	RefreshToken   string      `json:"-" db:"refresh_token" orm:"refresh_token"`
+	TokenExpiresAt *gtime.Time `json:"-" db:"token_expires_at" orm:"token_expires_at"`
+	ClientIp       string      `json:"clientIp" db:"client_ip"`         // 发起设备授权的客户端IP
+	UserAgent      string      `json:"userAgent" db:"user_agent"`       // 发起设备授权的客户端User-Agent
+	PollInterval   int         `json:"pollInterval" db:"poll_interval"` // 轮询间隔（秒），slow_down时增加
+	LastPolledAt   *gtime.Time `json:"lastPolledAt" db:"last_polled_at"`
+	ExpiresAt      *gtime.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt      *gtime.Time `json:"createdAt" db:"created_at"`
+}
+
+// DeviceTokenReq 设备令牌轮询请求
+type DeviceTokenReq struct {
+	GrantType  string `json:"grant_type"`
+	DeviceCode string `json:"device_code"`
+}
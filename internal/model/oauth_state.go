@@ -0,0 +1,13 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// OAuthState OAuth state参数记录（pgsql后端的StateStore使用）
+type OAuthState struct {
+	State     string      `json:"state" db:"state"`
+	Data      string      `json:"data" db:"data"`
+	ExpiresAt *gtime.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt *gtime.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// PersonalAccessToken 个人访问令牌模型（供脚本和CLI调用API，数据库只保存令牌摘要）
+type PersonalAccessToken struct {
+	Id         uint64      `json:"id" db:"id"`
+	UserId     uint64      `json:"userId" db:"user_id"`
+	Name       string      `json:"name" db:"name"`
+	TokenHash  string      `json:"-" db:"token_hash" orm:"token_hash"`
+	Prefix     string      `json:"prefix" db:"prefix"` // 令牌开头的若干字符，便于用户辨认
+	Scopes     string      `json:"scopes" db:"scopes"` // 以空格分隔的权限范围
+	ExpiresAt  *gtime.Time `json:"expiresAt" db:"expires_at"`
+	LastUsedAt *gtime.Time `json:"lastUsedAt" db:"last_used_at"`
+	LastUsedIp string      `json:"lastUsedIp" db:"last_used_ip"`
+	RevokedAt  *gtime.Time `json:"revokedAt" db:"revoked_at"`
+	CreatedAt  *gtime.Time `json:"createdAt" db:"created_at"`
+}
+
+// PersonalAccessTokenCreateReq 创建个人访问令牌请求
+type PersonalAccessTokenCreateReq struct {
+	Name          string   `json:"name" v:"required|length:1,100#令牌名称不能为空|令牌名称长度为1-100个字符"`
+	Scopes        []string `json:"scopes"`                               // 为空时使用默认权限范围
+	ExpiresInDays int      `json:"expires_in_days" v:"min:0#有效期天数不能为负数"` // 为0时使用默认有效期
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// RefreshToken 刷新令牌模型（同一次登录产生的令牌属于同一个family）
+type RefreshToken struct {
+	Id                  uint64      `json:"id" db:"id"`
+	FamilyId            string      `json:"familyId" db:"family_id"`
+	TokenHash           string      `json:"-" db:"token_hash" orm:"token_hash"`
+	Username            string      `json:"username" db:"username"`
+	CasdoorRefreshToken string      `json:"-" db:"casdoor_refresh_token" orm:"casdoor_refresh_token"`
+	ParentId            uint64      `json:"parentId" db:"parent_id"`
+	UsedAt              *gtime.Time `json:"usedAt" db:"used_at"`
+	RevokedAt           *gtime.Time `json:"revokedAt" db:"revoked_at"`
+	ExpiresAt           *gtime.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt           *gtime.Time `json:"createdAt" db:"created_at"`
+}
+
+// RefreshTokenReq 刷新令牌请求（浏览器可不传，使用HttpOnly Cookie）
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// RevokedToken 被吊销的访问令牌（按jti记录，过期后即可清理）
+type RevokedToken struct {
+	Jti       string      `json:"jti" db:"jti"`
+	Username  string      `json:"username" db:"username"`
+	ExpiresAt *gtime.Time `json:"expiresAt" db:"expires_at"`
+	CreatedAt *gtime.Time `json:"createdAt" db:"created_at"`
+}
+
+// UserTokenRevocation 用户级吊销记录，签发时间早于RevokedBefore的令牌全部失效
+type UserTokenRevocation struct {
+	Username      string      `json:"username" db:"username"`
+	RevokedBefore *gtime.Time `json:"revokedBefore" db:"revoked_before"`
+	UpdatedAt     *gtime.Time `json:"updatedAt" db:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// SchemaMigration 已应用的数据库迁移
+type SchemaMigration struct {
+	Version   int64       `json:"version" db:"version"`
+	Name      string      `json:"name" db:"name"`
+	AppliedAt *gtime.Time `json:"appliedAt" db:"applied_at"`
+}
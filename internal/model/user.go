@@ -4,23 +4,95 @@ import (
 	"github.com/gogf/gf/v2/os/gtime"
 )
 
+// 用户状态（取值与已有数据兼容，0为原"已停用"状态）
+const (
+	UserStatusDeleted   = 0 // 已删除（在Casdoor中被删除），不能再变更
+	UserStatusActive    = 1 // 正常
+	UserStatusSuspended = 2 // 被管理员暂停
+	UserStatusPending   = 3 // 待验证（邮箱尚未验证）
+)
+
+// userStatusNames 状态名称，用于错误码、审计和状态变更记录
+var userStatusNames = map[int]string{
+	UserStatusDeleted:   "deleted",
+	UserStatusActive:    "active",
+	UserStatusSuspended: "suspended",
+	UserStatusPending:   "pending_verification",
+}
+
+// userStatusTransitions 允许的状态变更
+var userStatusTransitions = map[int][]int{
+	UserStatusPending:   {UserStatusActive, UserStatusSuspended, UserStatusDeleted},
+	UserStatusActive:    {UserStatusSuspended, UserStatusDeleted},
+	UserStatusSuspended: {UserStatusActive, UserStatusDeleted},
+}
+
+// UserStatusName 返回状态名称，未知状态返回"unknown"
+func UserStatusName(status int) string {
+	if name, ok := userStatusNames[status]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// CanTransitionUserStatus 是否允许从from变更为to
+func CanTransitionUserStatus(from, to int) bool {
+	for _, allowed := range userStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
 // User 用户模型
 type User struct {
-	Id          uint64      `json:"id" db:"id"`
-	Username    string      `json:"username" db:"username"`
-	Email       string      `json:"email" db:"email"`
-	DisplayName string      `json:"displayName" db:"display_name"`
-	Avatar      string      `json:"avatar" db:"avatar"`
-	Phone       string      `json:"phone" db:"phone"`
-	Status      int         `json:"status" db:"status"`
-	CreatedAt   *gtime.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt   *gtime.Time `json:"updatedAt" db:"updated_at"`
+	Id              uint64      `json:"id" db:"id"`
+	CasdoorId       string      `json:"casdoorId" db:"casdoor_id" orm:"casdoor_id,omitempty"` // Casdoor用户的稳定ID（用户名可修改，ID不变）；为空时不写入，数据库中为NULL
+	Username        string      `json:"username" db:"username"`
+	Email           string      `json:"email" db:"email"`
+	DisplayName     string      `json:"displayName" db:"display_name"`
+	Avatar          string      `json:"avatar" db:"avatar"`
+	Phone           string      `json:"phone" db:"phone"`
+	Status          int         `json:"status" db:"status"`                     // 见UserStatus常量
+	StatusReason    string      `json:"statusReason" db:"status_reason"`        // 最近一次状态变更的原因
+	StatusChangedAt *gtime.Time `json:"statusChangedAt" db:"status_changed_at"` // 最近一次状态变更的时间
+	CreatedAt       *gtime.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt       *gtime.Time `json:"updatedAt" db:"updated_at"`
+	DeletedAt       *gtime.Time `json:"deletedAt" db:"deleted_at"`       // 软删除时间，删除后查询默认不返回该用户
+	AnonymizedAt    *gtime.Time `json:"anonymizedAt" db:"anonymized_at"` // 个人信息匿名化时间
+}
+
+// UserListReq 用户查询请求
+type UserListReq struct {
+	Keyword  string `json:"keyword"`  // 匹配用户名、邮箱、显示名称（不区分大小写）
+	Username string `json:"username"` // 按用户名精确过滤
+	Email    string `json:"email"`    // 按邮箱精确过滤
+	Status   *int   `json:"status"`
+	Sort     string `json:"sort" d:"id" v:"in:id,-id,username,-username,email,-email,created_at,-created_at,updated_at,-updated_at#不支持的排序字段"` // 前缀-表示降序
+	Page     int    `json:"page" d:"1" v:"min:1#页码必须大于0"`
+	PageSize int    `json:"page_size" d:"20" v:"between:1,100#每页数量为1-100"`
+}
+
+// UserUpdateReq 管理员修改用户资料请求，只修改提供的字段
+type UserUpdateReq struct {
+	DisplayName *string `json:"display_name" v:"length:1,100#显示名称长度为1-100"`
+	Email       *string `json:"email" v:"email#邮箱格式错误"`
+	Phone       *string `json:"phone" v:"max-length:20#手机号长度不能超过20"`
+	Avatar      *string `json:"avatar" v:"url#头像必须是URL"`
+}
+
+// UserStatusReq 管理员暂停/恢复用户请求
+type UserStatusReq struct {
+	Reason string `json:"reason" v:"max-length:500#原因长度不能超过500"`
 }
 
 // UserLoginReq 用户登录请求
 type UserLoginReq struct {
-	Code  string `json:"code" v:"required#授权码不能为空"`
-	State string `json:"state" v:"required#状态码不能为空"`
+	Code        string `json:"code" v:"required#授权码不能为空"`
+	State       string `json:"state" v:"required#状态码不能为空"`
+	RedirectURI string `json:"redirect_uri" v:"required#redirect_uri不能为空"`   // 必须与获取登录URL时的redirect_uri一致
+	Mode        string `json:"mode" v:"in:token,cookie#mode只能是token或cookie"` // cookie: 访问令牌保存在加密的HttpOnly Cookie中（未启用bff时回退为token）
 }
 
 // UserLoginRes 用户登录响应
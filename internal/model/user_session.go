@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// UserSession 用户登录会话（每次登录成功创建一条记录）
+type UserSession struct {
+	Id           uint64      `json:"id" db:"id"`
+	UserId       uint64      `json:"userId" db:"user_id"`
+	Username     string      `json:"username" db:"username"`
+	TokenId      string      `json:"-" db:"token_id" orm:"token_id"`   // 当前访问令牌的jti（刷新后更新）
+	FamilyId     string      `json:"-" db:"family_id" orm:"family_id"` // 对应的刷新令牌family
+	UserAgent    string      `json:"userAgent" db:"user_agent"`
+	Ip           string      `json:"ip" db:"ip"`
+	CreatedAt    *gtime.Time `json:"createdAt" db:"created_at"`
+	LastSeenAt   *gtime.Time `json:"lastSeenAt" db:"last_seen_at"`
+	ExpiresAt    *gtime.Time `json:"expiresAt" db:"expires_at"`
+	TerminatedAt *gtime.Time `json:"terminatedAt" db:"terminated_at"`
+}
+
+// UserSessionRes 会话列表项
+type UserSessionRes struct {
+	*UserSession
+	Current bool `json:"current"` // 是否为发起请求的会话
+}
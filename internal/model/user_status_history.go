@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// 用户状态变更来源
+const (
+	UserStatusSourceAdmin     = "admin"     // 管理员操作
+	UserStatusSourceUser      = "user"      // 用户本人操作（如删除账号）
+	UserStatusSourceSync      = "sync"      // 登录或同步时根据Casdoor资料变更（如邮箱已验证）
+	UserStatusSourceWebhook   = "webhook"   // Casdoor Webhook
+	UserStatusSourceReconcile = "reconcile" // 定时或手动对账
+)
+
+// UserStatusChange 一次用户状态变更记录
+type UserStatusChange struct {
+	Id         uint64      `json:"id" db:"id"`
+	UserId     uint64      `json:"userId" db:"user_id"`
+	FromStatus int         `json:"fromStatus" db:"from_status"`
+	ToStatus   int         `json:"toStatus" db:"to_status"`
+	Reason     string      `json:"reason" db:"reason"`
+	Source     string      `json:"source" db:"source"` // 见UserStatusSource常量
+	Actor      string      `json:"actor" db:"actor"`   // 执行变更的管理员，非管理员操作时为空
+	CreatedAt  *gtime.Time `json:"createdAt" db:"created_at"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// Webhook事件处理结果
+const (
+	WebhookEventProcessing = "processing" // 处理中
+	WebhookEventProcessed  = "processed"  // 已处理
+	WebhookEventIgnored    = "ignored"    // 不关心的事件，未处理
+)
+
+// WebhookEvent 已接收的Webhook事件，按事件ID去重，保证重复投递只处理一次
+type WebhookEvent struct {
+	Id         uint64      `json:"id" db:"id"`
+	EventId    string      `json:"eventId" db:"event_id"`
+	Source     string      `json:"source" db:"source"` // 事件来源（如casdoor）
+	Action     string      `json:"action" db:"action"`
+	Username   string      `json:"username" db:"username"`
+	Status     string      `json:"status" db:"status"` // 见WebhookEvent常量
+	ReceivedAt *gtime.Time `json:"receivedAt" db:"received_at"`
+}
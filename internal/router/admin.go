@@ -0,0 +1,28 @@
+package router
+
+import (
+	"context-id-backend/internal/controller"
+	"context-id-backend/internal/middleware"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// RegisterAdminRoutes 注册管理员接口路由
+func RegisterAdminRoutes(group *ghttp.RouterGroup) {
+	group.Group("/admin", func(adminGroup *ghttp.RouterGroup) {
+		adminGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser, middleware.RequireAdmin)
+
+		// 用户管理
+		adminGroup.GET("/users", controller.AdminUser.List)
+		adminGroup.GET("/users/{id}", controller.AdminUser.Get)
+		adminGroup.GET("/users/{id}/status-history", controller.AdminUser.StatusHistory)
+
+		// 修改用户要求令牌具有write权限范围
+		adminGroup.Group("/", func(writeGroup *ghttp.RouterGroup) {
+			writeGroup.Middleware(middleware.RequireScopes("write"))
+			writeGroup.PATCH("/users/{id}", controller.AdminUser.Update)
+			writeGroup.POST("/users/{id}/suspend", controller.AdminUser.Suspend)
+			writeGroup.POST("/users/{id}/reactivate", controller.AdminUser.Reactivate)
+		})
+	})
+}
@@ -18,10 +18,26 @@ func RegisterAPIRoutes(group *ghttp.RouterGroup) {
 					"login_url":      "/api/v1/auth/login-url",
 					"signup_url":     "/api/v1/auth/signup-url",
 					"token_exchange": "/api/v1/auth/callback", // POST: 前端用code+state交换token
+					"token_refresh":  "/api/v1/auth/refresh",  // POST: 使用刷新令牌换取新的访问令牌
 					"user_info":      "/api/v1/user",
+					"jwks":           "/api/v1/auth/jwks",
+					"service_token":  "/api/v1/auth/token",        // POST: client_credentials换取服务令牌
+					"device_code":    "/api/v1/auth/device/code",  // POST: 设备码登录（CLI）
+					"device_token":   "/api/v1/auth/device/token", // POST: 轮询设备码登录结果
 				},
 				"protected": g.Map{
-					"my_profile": "/api/v1/auth/my-profile-url",
+					"my_profile":      "/api/v1/auth/my-profile-url",
+					"permissions":     "/api/v1/auth/permissions", // GET: 当前用户的角色和权限
+					"logout":          "/api/v1/auth/logout",      // POST: 登出当前会话
+					"logout_all":      "/api/v1/auth/logout/all",  // POST: 登出所有会话
+					"sessions":        "/api/v1/auth/sessions",    // GET: 登录会话列表，DELETE /api/v1/auth/sessions/{id} 结束会话
+					"delete_account":  "/api/v1/user",             // DELETE: 删除当前用户的账号
+					"my_audit":        "/api/v1/user/audit",       // GET: 当前用户的审计记录
+					"export":          "/api/v1/user/export",      // GET: 导出个人数据（zip），数据量大时返回202和下载链接，GET /api/v1/user/exports/{id} 查询状态
+					"audit":           "/api/v1/audit",            // GET: 所有审计记录（管理员）
+					"tokens":          "/api/v1/tokens",           // GET/POST: 个人访问令牌，DELETE /api/v1/tokens/{id} 吊销
+					"casdoor_webhook": "/api/v1/webhooks/casdoor", // POST: Casdoor用户事件Webhook（X-Webhook-Token或HMAC签名）
+					"admin_users":     "/api/v1/admin/users",      // GET: 用户列表（管理员），GET/PATCH /api/v1/admin/users/{id}，POST .../{id}/suspend、.../{id}/reactivate，GET .../{id}/status-history
 				},
 			},
 		})
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"context-id-backend/internal/controller"
+	"context-id-backend/internal/middleware"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// RegisterAuditRoutes 注册审计日志相关路由
+func RegisterAuditRoutes(group *ghttp.RouterGroup) {
+	// 所有审计事件，仅管理员可查询
+	group.Group("/audit", func(auditGroup *ghttp.RouterGroup) {
+		auditGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser, middleware.RequireAdmin)
+		auditGroup.GET("/", controller.Audit.List)
+	})
+
+	// 当前用户自己的审计事件
+	group.Group("/user", func(userGroup *ghttp.RouterGroup) {
+		userGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser)
+		userGroup.GET("/audit", controller.Audit.ListMine)
+	})
+}
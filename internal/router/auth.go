@@ -9,21 +9,56 @@ import (
 
 // RegisterAuthRoutes 注册认证相关路由
 func RegisterAuthRoutes(group *ghttp.RouterGroup) {
-	// 认证相关路由 - 不需要认证的公开API
-	group.GET("/auth/login-url", controller.Auth.GetLoginURL)   // 获取Casdoor登录URL
-	group.GET("/auth/signup-url", controller.Auth.GetSignupURL) // 获取Casdoor注册URL
+	// 认证相关路由 - 不需要认证的公开API（按客户端IP限流）
+	group.Group("/auth", func(publicGroup *ghttp.RouterGroup) {
+		publicGroup.Middleware(middleware.RateLimitByIP)
+		publicGroup.GET("/login-url", controller.Auth.GetLoginURL)   // 获取Casdoor登录URL
+		publicGroup.GET("/signup-url", controller.Auth.GetSignupURL) // 获取Casdoor注册URL
+		publicGroup.GET("/jwks", controller.Auth.GetJWKS)            // 本地会话Token签名公钥
 
-	// OAuth 2.0 Token交换API (前后端分离架构)
-	// 注意: 这是后端API，不是OAuth回调URL
-	// Casdoor的redirect_uri应该指向前端页面，而不是这个API
-	group.POST("/auth/callback", controller.Auth.Login) // 前端用code+state交换token
+		// OAuth 2.0 Token交换API (前后端分离架构)
+		// 注意: 这是后端API，不是OAuth回调URL
+		// Casdoor的redirect_uri应该指向前端页面，而不是这个API
+		publicGroup.POST("/callback", controller.Auth.Login)          // 前端用code+state交换token
+		publicGroup.POST("/refresh", controller.Auth.Refresh)         // 使用刷新令牌换取新的访问令牌
+		publicGroup.POST("/token", controller.Auth.IssueServiceToken) // 服务间调用：client_credentials换取应用令牌
+
+		// OAuth 2.0 设备码登录 (RFC 8628，供CLI使用)
+		publicGroup.POST("/device/code", controller.Device.Code)        // CLI获取device_code和user_code
+		publicGroup.POST("/device/token", controller.Device.Token)      // CLI轮询令牌
+		publicGroup.GET("/device/verify", controller.Device.Confirm)    // 浏览器输入user_code后获取确认信息和CSRF令牌
+		publicGroup.POST("/device/verify", controller.Device.Verify)    // 浏览器确认后跳转到Casdoor登录
+		publicGroup.GET("/device/callback", controller.Device.Callback) // Casdoor登录完成后的回调
+	})
 
 	// 用户信息相关API
-	group.GET("/user", controller.Auth.GetCurrentUser) // 通过token获取用户信息
+	group.Group("/", func(userGroup *ghttp.RouterGroup) {
+		userGroup.Middleware(middleware.RateLimitByIP)
+		userGroup.GET("/user", controller.Auth.GetCurrentUser) // 通过token获取用户信息
+	})
+	group.Group("/", func(accountGroup *ghttp.RouterGroup) {
+		accountGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser, middleware.RequireScopes("write"))
+		accountGroup.DELETE("/user", controller.Account.Delete) // 删除当前用户的账号
+	})
 
 	// 认证相关路由 - 需要认证的受保护API
+	// 先按IP限流（无效令牌同样计数），认证后再按调用方限流
+	// 需要授权的路由组在Auth之后追加授权中间件，例如：
+	//   group.Middleware(middleware.Auth, middleware.RequireRoles("editor"))
+	//   group.Middleware(middleware.Auth, middleware.RequirePermissions("read-report"))
+	//   group.Middleware(middleware.Auth, middleware.RequireEnforce("org/permission", "/reports", "read"))
 	group.Group("/auth", func(authGroup *ghttp.RouterGroup) {
-		authGroup.Middleware(middleware.Auth)
+		authGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser)
 		authGroup.GET("/profile-url", controller.Auth.GetMyProfileURL) // 获取用户资料页面URL
+		authGroup.GET("/permissions", controller.Auth.GetPermissions)  // 当前用户的角色和权限
+		authGroup.GET("/sessions", controller.Session.List)            // 列出当前用户的登录会话
+
+		// 修改状态的操作要求令牌具有write权限范围（只读的个人访问令牌不能调用）
+		authGroup.Group("/", func(writeGroup *ghttp.RouterGroup) {
+			writeGroup.Middleware(middleware.RequireScopes("write"))
+			writeGroup.POST("/logout", controller.Auth.Logout)                // 登出当前会话
+			writeGroup.POST("/logout/all", controller.Auth.LogoutAll)         // 登出所有会话
+			writeGroup.DELETE("/sessions/{id}", controller.Session.Terminate) // 结束指定会话
+		})
 	})
 }
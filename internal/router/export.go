@@ -0,0 +1,23 @@
+package router
+
+import (
+	"context-id-backend/internal/controller"
+	"context-id-backend/internal/middleware"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// RegisterExportRoutes 注册个人数据导出路由
+func RegisterExportRoutes(group *ghttp.RouterGroup) {
+	group.Group("/user", func(userGroup *ghttp.RouterGroup) {
+		userGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser)
+		userGroup.GET("/export", controller.Export.Export)    // 导出个人数据（数据量大时异步生成）
+		userGroup.GET("/exports/{id}", controller.Export.Get) // 异步导出的状态
+	})
+
+	// 下载链接中的令牌即凭证，不经过认证中间件（浏览器可直接打开链接下载）
+	group.Group("/user/exports", func(downloadGroup *ghttp.RouterGroup) {
+		downloadGroup.Middleware(middleware.RateLimitByIP)
+		downloadGroup.GET("/{id}/download", controller.Export.Download)
+	})
+}
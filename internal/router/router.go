@@ -17,6 +17,21 @@ func InitRoutes(s *ghttp.Server) {
 
 		// 认证相关路由
 		RegisterAuthRoutes(v1Group)
+
+		// 个人访问令牌路由
+		RegisterTokenRoutes(v1Group)
+
+		// 审计日志路由
+		RegisterAuditRoutes(v1Group)
+
+		// 个人数据导出路由
+		RegisterExportRoutes(v1Group)
+
+		// Webhook接收路由
+		RegisterWebhookRoutes(v1Group)
+
+		// 管理员接口路由
+		RegisterAdminRoutes(v1Group)
 	})
 
 	// 根路径处理 - 返回服务器信息
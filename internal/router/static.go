@@ -27,6 +27,11 @@ func RegisterStaticRoutes(s *ghttp.Server) {
 		r.Response.ServeFile("static/error.html")
 	})
 
+	// 设备码登录页面（CLI登录时在浏览器中输入用户码）
+	s.BindHandler("/device", func(r *ghttp.Request) {
+		r.Response.ServeFile("static/device.html")
+	})
+
 	// Template测试页面路由
 	s.BindHandler("/template/login", func(r *ghttp.Request) {
 		r.Response.ServeFile("templates/index.html")
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"context-id-backend/internal/controller"
+	"context-id-backend/internal/middleware"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// RegisterTokenRoutes 注册个人访问令牌相关路由
+func RegisterTokenRoutes(group *ghttp.RouterGroup) {
+	group.Group("/tokens", func(tokenGroup *ghttp.RouterGroup) {
+		tokenGroup.Middleware(middleware.RateLimitByIP, middleware.Auth, middleware.RateLimitByUser)
+		tokenGroup.GET("/", controller.Token.List)          // 列出个人访问令牌
+		tokenGroup.POST("/", controller.Token.Create)       // 创建个人访问令牌
+		tokenGroup.DELETE("/{id}", controller.Token.Revoke) // 吊销个人访问令牌
+	})
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"context-id-backend/internal/controller"
+	"context-id-backend/internal/middleware"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// RegisterWebhookRoutes 注册Webhook接收路由（通过共享密钥或请求签名认证，不使用Bearer token）
+func RegisterWebhookRoutes(group *ghttp.RouterGroup) {
+	group.Group("/webhooks", func(webhookGroup *ghttp.RouterGroup) {
+		webhookGroup.Middleware(middleware.RateLimitByIP)
+		webhookGroup.POST("/casdoor", controller.Webhook.Casdoor)
+	})
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+const (
+	// accountAnonymizeInterval 匿名化任务的执行间隔
+	accountAnonymizeInterval = time.Hour
+	// accountAnonymizeBatch 匿名化任务每批处理的用户数
+	accountAnonymizeBatch = 100
+)
+
+// AccountConfig 账号删除配置结构体
+type AccountConfig struct {
+	ErasureGracePeriod time.Duration // 删除账号后保留个人信息的时间，到期后匿名化
+}
+
+// AccountService 用户删除自己的账号：删除Casdoor用户、软删除本地用户，宽限期后匿名化个人信息
+type AccountService struct {
+	config *AccountConfig
+}
+
+var Account = &AccountService{}
+
+// Init 加载账号删除配置，并启动匿名化任务
+func (s *AccountService) Init(ctx context.Context) error {
+	config := &AccountConfig{
+		ErasureGracePeriod: 30 * 24 * time.Hour,
+	}
+
+	if gracePeriod, err := g.Cfg().Get(ctx, "account.erasureGracePeriod"); err == nil && !gracePeriod.IsEmpty() {
+		config.ErasureGracePeriod = gracePeriod.Duration()
+	}
+
+	// 环境变量覆盖配置文件
+	if gracePeriod := os.Getenv("ACCOUNT_ERASURE_GRACE_PERIOD"); gracePeriod != "" {
+		duration, err := time.ParseDuration(gracePeriod)
+		if err != nil {
+			return fmt.Errorf("ACCOUNT_ERASURE_GRACE_PERIOD 格式错误: %w", err)
+		}
+		config.ErasureGracePeriod = duration
+	}
+
+	if config.ErasureGracePeriod < 0 {
+		return fmt.Errorf("account.erasureGracePeriod 不能为负数")
+	}
+
+	s.config = config
+	go s.anonymizeDeleted()
+
+	g.Log().Info(ctx, "✅ 账号删除配置加载完成，个人信息保留期:", config.ErasureGracePeriod)
+	return nil
+}
+
+// Delete 删除用户自己的账号
+// 先删除Casdoor用户，失败时本地不做修改，用户可以重试；之后本地用户变为已删除（令牌和会话随即失效）并被软删除
+func (s *AccountService) Delete(ctx context.Context, user *model.User) error {
+	if err := Casdoor.deleteCasdoorUser(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.markDeleted(ctx, user, "deleted by user", model.UserStatusSourceUser); err != nil {
+		return err
+	}
+
+	anonymizeAt := gtime.Now().Add(s.config.ErasureGracePeriod)
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventAccountDeleted,
+		UserId:    user.Id,
+		Username:  user.Username,
+		Success:   true,
+		Detail:    g.Map{"anonymizeAt": anonymizeAt},
+	})
+	g.Log().Info(ctx, "Account deleted by user:", user.Username, "anonymize at:", anonymizeAt)
+	return nil
+}
+
+// markDeleted 将用户变为已删除状态并软删除（释放用户名和邮箱，保留期后匿名化）
+// 用户自行删除账号和Casdoor中删除用户（Webhook、对账）都经过这里
+func (s *AccountService) markDeleted(ctx context.Context, user *model.User, reason, source string) error {
+	// Casdoor的删除事件可能已先一步通过Webhook处理，此时用户已是已删除状态
+	if user.Status != model.UserStatusDeleted {
+		err := UserStatus.Transition(ctx, user, model.UserStatusDeleted, reason, source)
+		if err != nil && !errors.Is(err, ErrUserStatusConflict) {
+			return err
+		}
+	}
+
+	if _, err := Casdoor.users.SoftDelete(ctx, user.Id); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	// 导出包包含完整的个人数据，不等到匿名化
+	if err := dao.DataExport.DeleteByUser(ctx, user.Id); err != nil {
+		g.Log().Warning(ctx, "Failed to delete data exports of deleted user:", user.Username, err)
+	}
+	return nil
+}
+
+// Anonymize 匿名化超过保留期的已删除用户，返回处理的用户数
+func (s *AccountService) Anonymize(ctx context.Context) (int, error) {
+	before := gtime.Now().Add(-s.config.ErasureGracePeriod)
+	count := 0
+	for {
+		users, err := Casdoor.users.ListToAnonymize(ctx, before, accountAnonymizeBatch)
+		if err != nil {
+			return count, err
+		}
+		for _, user := range users {
+			if err := Casdoor.users.Anonymize(ctx, user.Id); err != nil {
+				return count, fmt.Errorf("failed to anonymize user %d: %w", user.Id, err)
+			}
+			Audit.Record(ctx, &model.AuditEvent{
+				EventType: model.AuditEventUserAnonymized,
+				UserId:    user.Id,
+				Username:  user.Username,
+				Success:   true,
+			})
+			count++
+		}
+		if len(users) < accountAnonymizeBatch {
+			return count, nil
+		}
+	}
+}
+
+// anonymizeDeleted 定期匿名化超过保留期的已删除用户
+func (s *AccountService) anonymizeDeleted() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(accountAnonymizeInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := s.Anonymize(ctx)
+		if err != nil {
+			g.Log().Warning(ctx, "Failed to anonymize deleted users:", err)
+		}
+		if count > 0 {
+			g.Log().Info(ctx, "Anonymized deleted users:", count)
+		}
+	}
+}
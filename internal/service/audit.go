@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// AuditService 审计日志服务：记录登录、登出、令牌拒绝等安全相关事件
+type AuditService struct{}
+
+var Audit = &AuditService{}
+
+// Record 写入审计事件，自动补充IP、User-Agent、请求ID，以及已认证调用方的用户信息
+// 写入失败只记录日志，不影响业务流程
+func (s *AuditService) Record(ctx context.Context, event *model.AuditEvent) {
+	event.CreatedAt = gtime.Now()
+	event.RequestId = gctx.CtxId(ctx)
+
+	if r := g.RequestFromCtx(ctx); r != nil {
+		event.Ip = r.GetClientIp()
+		event.UserAgent = truncate(r.UserAgent(), 500)
+		if requestId := r.Header.Get("X-Request-Id"); requestId != "" {
+			event.RequestId = truncate(requestId, 64)
+		}
+	}
+	if identity := currentIdentity(ctx); identity != nil && identity.User != nil && event.UserId == 0 {
+		event.UserId = identity.User.Id
+		event.Username = identity.User.Username
+	}
+	event.Reason = truncate(event.Reason, 500)
+
+	if err := dao.AuditEvent.Create(ctx, event); err != nil {
+		g.Log().Error(ctx, "Failed to record audit event:", event.EventType, err)
+	}
+}
+
+// currentIdentity 获取当前请求已认证的调用方身份，不在请求中或未认证时返回nil
+func currentIdentity(ctx context.Context) *Identity {
+	r := g.RequestFromCtx(ctx)
+	if r == nil {
+		return nil
+	}
+	identity, _ := r.GetCtxVar("identity").Interface().(*Identity)
+	return identity
+}
+
+// LoginSucceeded 记录登录成功，flow为登录方式（如authorization_code、device_code）
+func (s *AuditService) LoginSucceeded(ctx context.Context, user *model.User, flow string) {
+	s.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventLoginSuccess,
+		UserId:    user.Id,
+		Username:  user.Username,
+		Success:   true,
+		Detail:    g.Map{"flow": flow},
+	})
+}
+
+// LoginFailed 记录登录失败
+func (s *AuditService) LoginFailed(ctx context.Context, flow string, err error) {
+	s.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventLoginFailure,
+		Reason:    err.Error(),
+		Detail:    g.Map{"flow": flow},
+	})
+}
+
+// List 分页查询审计事件
+func (s *AuditService) List(ctx context.Context, req *model.AuditEventListReq) ([]*model.AuditEvent, int, error) {
+	return dao.AuditEvent.List(ctx, req)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+const (
+	// BFFSessionCookie 保存加密访问令牌的会话Cookie（HttpOnly）
+	BFFSessionCookie = "cid_session"
+	// BFFCSRFCookie 保存CSRF令牌的Cookie（前端脚本可读，用于双重提交）
+	BFFCSRFCookie = "cid_csrf"
+	// BFFCSRFHeader 修改类请求需要携带的CSRF令牌请求头
+	BFFCSRFHeader = "X-CSRF-Token"
+	// bffMaxCookieSize 浏览器对单个Cookie的大小限制
+	bffMaxCookieSize = 4096
+)
+
+var (
+	// ErrBFFDisabled 未启用Cookie会话模式
+	ErrBFFDisabled = errors.New("cookie session mode is disabled")
+	// ErrBFFSessionInvalid 会话Cookie无法解密或已被篡改
+	ErrBFFSessionInvalid = errors.New("invalid session cookie")
+	// ErrBFFCookieTooLarge 加密后的会话超过Cookie大小限制
+	ErrBFFCookieTooLarge = errors.New("session cookie exceeds the browser size limit")
+)
+
+// BFFConfig Cookie会话模式配置结构体
+type BFFConfig struct {
+	Enabled bool   // 是否启用Cookie会话模式
+	Secret  string // 会话Cookie的加密密钥
+}
+
+// BFFSession 会话Cookie中加密保存的内容
+type BFFSession struct {
+	AccessToken string `json:"t"`
+	CSRFToken   string `json:"c"`
+}
+
+// BFFService Cookie会话模式（backend-for-frontend）：访问令牌加密后存放在HttpOnly Cookie中，
+// 浏览器脚本无法读取；修改类请求使用双重提交的CSRF令牌防护
+type BFFService struct {
+	config *BFFConfig
+	aead   cipher.AEAD
+}
+
+var BFF = &BFFService{}
+
+// Init 加载Cookie会话模式配置
+func (s *BFFService) Init(ctx context.Context) error {
+	config := &BFFConfig{}
+	cfg := g.Cfg()
+
+	if enabled, err := cfg.Get(ctx, "bff.enabled"); err == nil && !enabled.IsEmpty() {
+		config.Enabled = enabled.Bool()
+	}
+	if secret, err := cfg.Get(ctx, "bff.secret"); err == nil && !secret.IsEmpty() {
+		config.Secret = secret.String()
+	}
+
+	// 环境变量覆盖配置文件
+	if enabled := os.Getenv("BFF_ENABLED"); enabled != "" {
+		config.Enabled = enabled == "true"
+	}
+	if secret := os.Getenv("BFF_SECRET"); secret != "" {
+		config.Secret = secret
+	}
+
+	s.config = config
+	if !config.Enabled {
+		g.Log().Info(ctx, "Cookie会话模式未启用")
+		return nil
+	}
+
+	if len(config.Secret) < 32 {
+		return fmt.Errorf("bff.secret 至少需要32个字符")
+	}
+
+	// 由配置的密钥派生AES-256密钥
+	key := sha256.Sum256([]byte(config.Secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return fmt.Errorf("初始化会话加密失败: %w", err)
+	}
+	s.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("初始化会话加密失败: %w", err)
+	}
+
+	g.Log().Info(ctx, "✅ Cookie会话模式已启用")
+	return nil
+}
+
+// Enabled 是否启用Cookie会话模式
+func (s *BFFService) Enabled() bool {
+	return s.config != nil && s.config.Enabled
+}
+
+// NewSession 为登录成功的访问令牌创建会话，并生成新的CSRF令牌
+func (s *BFFService) NewSession(accessToken string) (*BFFSession, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate csrf token: %w", err)
+	}
+	return &BFFSession{
+		AccessToken: accessToken,
+		CSRFToken:   base64.RawURLEncoding.EncodeToString(token),
+	}, nil
+}
+
+// Seal 压缩并加密会话，返回Cookie值
+func (s *BFFService) Seal(session *BFFSession) (string, error) {
+	if !s.Enabled() {
+		return "", ErrBFFDisabled
+	}
+
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+
+	// JWT本身是base64文本，压缩后可抵消加密结果再次编码带来的膨胀
+	var compressed bytes.Buffer
+	writer, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write(payload); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, compressed.Bytes(), []byte(BFFSessionCookie))
+
+	value := base64.RawURLEncoding.EncodeToString(sealed)
+	if len(BFFSessionCookie)+len(value) > bffMaxCookieSize {
+		return "", ErrBFFCookieTooLarge
+	}
+	return value, nil
+}
+
+// Open 解密会话Cookie
+func (s *BFFService) Open(value string) (*BFFSession, error) {
+	if !s.Enabled() {
+		return nil, ErrBFFDisabled
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return nil, ErrBFFSessionInvalid
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	compressed, err := s.aead.Open(nil, nonce, ciphertext, []byte(BFFSessionCookie))
+	if err != nil {
+		return nil, ErrBFFSessionInvalid
+	}
+
+	payload, err := io.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		return nil, ErrBFFSessionInvalid
+	}
+
+	var session *BFFSession
+	if err := json.Unmarshal(payload, &session); err != nil || session == nil || session.AccessToken == "" {
+		return nil, ErrBFFSessionInvalid
+	}
+	return session, nil
+}
+
+// SessionFromRequest 读取请求中的会话Cookie，未启用或没有Cookie时返回nil
+func (s *BFFService) SessionFromRequest(r *ghttp.Request) (*BFFSession, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+	value := r.Cookie.Get(BFFSessionCookie).String()
+	if value == "" {
+		return nil, nil
+	}
+	return s.Open(value)
+}
+
+// CheckCSRF 双重提交校验：修改类请求的X-CSRF-Token必须与会话中的CSRF令牌一致
+// 会话Cookie加密保存了CSRF令牌，攻击者写入的csrf Cookie无法通过校验
+func (s *BFFService) CheckCSRF(r *ghttp.Request, session *BFFSession) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	token := r.Header.Get(BFFCSRFHeader)
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
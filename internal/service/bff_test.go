@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// newBFFTestService 使用指定密钥启用Cookie会话模式
+func newBFFTestService(t *testing.T, secret string) *BFFService {
+	t.Helper()
+	t.Setenv("BFF_ENABLED", "true")
+	t.Setenv("BFF_SECRET", secret)
+	s := &BFFService{}
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return s
+}
+
+// TestBFFSealOpen 会话Cookie加密往返，篡改、截断或换用其他密钥的Cookie无法解密
+func TestBFFSealOpen(t *testing.T) {
+	const secret = "0123456789abcdef0123456789abcdef"
+	s := newBFFTestService(t, secret)
+
+	session, err := s.NewSession("header.payload.signature")
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	value, err := s.Seal(session)
+	if err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+
+	opened, err := s.Open(value)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if opened.AccessToken != session.AccessToken || opened.CSRFToken != session.CSRFToken {
+		t.Fatalf("Open: got %+v, want %+v", opened, session)
+	}
+
+	tampered := []byte(value)
+	index := len(tampered) / 2
+	if tampered[index] == 'A' {
+		tampered[index] = 'B'
+	} else {
+		tampered[index] = 'A'
+	}
+
+	cases := []struct {
+		name    string
+		service *BFFService
+		value   string
+	}{
+		{"tampered", s, string(tampered)},
+		{"truncated", s, value[:len(value)-4]},
+		{"too short", s, value[:8]},
+		{"not base64", s, "!" + value[1:]},
+		{"empty", s, ""},
+		{"wrong secret", newBFFTestService(t, strings.Repeat("x", 32)), value},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.service.Open(tc.value); !errors.Is(err, ErrBFFSessionInvalid) {
+				t.Fatalf("Open: got %v, want ErrBFFSessionInvalid", err)
+			}
+		})
+	}
+}
+
+// TestBFFDisabled 未启用Cookie会话模式时不能加密或解密
+func TestBFFDisabled(t *testing.T) {
+	s := &BFFService{config: &BFFConfig{}}
+	if _, err := s.Seal(&BFFSession{AccessToken: "token"}); !errors.Is(err, ErrBFFDisabled) {
+		t.Fatalf("Seal: got %v, want ErrBFFDisabled", err)
+	}
+	if _, err := s.Open("value"); !errors.Is(err, ErrBFFDisabled) {
+		t.Fatalf("Open: got %v, want ErrBFFDisabled", err)
+	}
+}
+
+// TestBFFCheckCSRF 只读请求不需要CSRF令牌，修改类请求必须携带与会话一致的X-CSRF-Token
+func TestBFFCheckCSRF(t *testing.T) {
+	s := &BFFService{}
+	session := &BFFSession{AccessToken: "token", CSRFToken: "csrf-token"}
+
+	cases := []struct {
+		name   string
+		method string
+		header string
+		pass   bool
+	}{
+		{"get without header", http.MethodGet, "", true},
+		{"head without header", http.MethodHead, "", true},
+		{"options without header", http.MethodOptions, "", true},
+		{"post with matching header", http.MethodPost, "csrf-token", true},
+		{"post without header", http.MethodPost, "", false},
+		{"post with mismatched header", http.MethodPost, "other-token", false},
+		{"post with prefix of token", http.MethodPost, "csrf", false},
+		{"delete without header", http.MethodDelete, "", false},
+		{"put with mismatched header", http.MethodPut, "other-token", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, "/api/v1/user", nil)
+			if tc.header != "" {
+				request.Header.Set(BFFCSRFHeader, tc.header)
+			}
+			r := &ghttp.Request{Request: request}
+			if got := s.CheckCSRF(r, session); got != tc.pass {
+				t.Fatalf("CheckCSRF: got %v, want %v", got, tc.pass)
+			}
+		})
+	}
+}
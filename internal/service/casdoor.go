@@ -5,18 +5,24 @@ import (
 	"context-id-backend/internal/dao"
 	"context-id-backend/internal/model"
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
 	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
 	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/joho/godotenv"
+	"golang.org/x/oauth2"
 )
 
 // CasdoorConfig Casdoor配置结构体
@@ -28,28 +34,42 @@ type CasdoorConfig struct {
 	JwtSecret        string // JWT公钥，仅支持PEM格式公钥文件内容
 	OrganizationName string
 	ApplicationName  string
+	UpstreamLogout   bool   // 登出时是否同时调用Casdoor的登出接口
+	PKCE             bool   // 授权码流程是否启用PKCE（S256）
+	JwksURL          string // Casdoor JWKS地址，用于按kid获取签名公钥（支持证书轮换）
+	Issuer           string // 期望的iss，留空则接受Casdoor内部/外部地址
 }
 
 // AppConfig 应用配置结构体
 type AppConfig struct {
-	ExternalUrl        string // 应用外部访问地址
-	CasdoorExternalUrl string // Casdoor外部访问地址
+	ExternalUrl          string   // 应用外部访问地址
+	CasdoorExternalUrl   string   // Casdoor外部访问地址
+	StateStore           string   // state存储后端：memory / redis / pgsql
+	Environment          string   // 运行环境，用于选择redirect_uri白名单
+	RedirectAllowlist    []string // redirect_uri白名单
+	AdminRoles           []string // 视为管理员的Casdoor角色
+	MaxStates            int      // 未完成登录的state数量上限
+	RequireVerifiedEmail bool     // 邮箱未验证的新用户为待验证状态
 }
 
-// StateStore State参数存储（用于CSRF防护）
-type StateStore struct {
-	states map[string]time.Time // state -> 过期时间
-	mutex  sync.RWMutex
-}
+// ErrTooManyStates 未完成的登录请求过多，拒绝生成新的state
+var ErrTooManyStates = errors.New("too many pending login requests")
 
 // CasdoorService Casdoor认证服务
 type CasdoorService struct {
-	config     *CasdoorConfig
-	appConfig  *AppConfig
-	stateStore *StateStore
+	config      *CasdoorConfig
+	appConfig   *AppConfig
+	stateStore  StateStore
+	keyProvider *KeyProvider
+	users       dao.UserRepository // 本地用户存储，默认使用数据库实现
 }
 
-var Casdoor = &CasdoorService{}
+var Casdoor = &CasdoorService{users: dao.User}
+
+// SetUserRepository 替换本地用户存储（如测试时使用内存实现）
+func (s *CasdoorService) SetUserRepository(users dao.UserRepository) {
+	s.users = users
+}
 
 // Init 初始化Casdoor客户端 (参考tutorial的配置加载方式)
 func (s *CasdoorService) Init(ctx context.Context) error {
@@ -82,24 +102,33 @@ func (s *CasdoorService) Init(ctx context.Context) error {
 		return fmt.Errorf("配置验证失败: %w", err)
 	}
 
-	s.config = config
-
-	// 4. 初始化state存储
-	s.stateStore = &StateStore{
-		states: make(map[string]time.Time),
+	// 未单独配置JWKS地址时使用Casdoor的标准地址
+	if config.JwksURL == "" {
+		config.JwksURL = strings.TrimRight(config.Endpoint, "/") + "/.well-known/jwks"
 	}
 
-	// 启动state清理goroutine
-	go s.cleanupExpiredStates()
+	s.config = config
+
+	// 初始化签名公钥提供者（JWKS优先，配置的PEM公钥兜底）
+	s.keyProvider = newKeyProvider(ctx, config.JwksURL, config.JwtSecret)
 
-	// 5. 加载应用配置
+	// 4. 加载应用配置
 	appConfig, err := s.loadAppConfig(ctx)
 	if err != nil {
 		return fmt.Errorf("应用配置加载失败: %w", err)
 	}
 	s.appConfig = appConfig
 
-	// 5. 初始化Casdoor全局配置
+	// 5. 初始化state存储
+	s.stateStore, err = newStateStore(ctx, appConfig.StateStore)
+	if err != nil {
+		return fmt.Errorf("state存储初始化失败: %w", err)
+	}
+
+	// 启动state清理goroutine
+	go s.cleanupExpiredStates()
+
+	// 6. 初始化Casdoor全局配置
 	casdoorsdk.InitConfig(
 		config.Endpoint,
 		config.ClientId,
@@ -115,6 +144,8 @@ func (s *CasdoorService) Init(ctx context.Context) error {
 	g.Log().Info(ctx, "   - ClientId:", config.ClientId)
 	g.Log().Info(ctx, "   - Organization:", config.OrganizationName)
 	g.Log().Info(ctx, "   - Application:", config.ApplicationName)
+	g.Log().Info(ctx, "   - PKCE:", config.PKCE)
+	g.Log().Info(ctx, "   - JWKS URL:", config.JwksURL)
 
 	// 记录JWT密钥信息
 	if strings.Contains(config.JwtSecret, "-----BEGIN PUBLIC KEY-----") {
@@ -136,67 +167,127 @@ func (s *CasdoorService) safeSubstring(str string, maxLen int) string {
 	return str[:maxLen]
 }
 
-// generateState 生成随机state参数（用于CSRF防护）
-func (s *CasdoorService) generateState(ctx context.Context) (string, error) {
+// generateState 生成随机state参数（用于CSRF防护），并保存entry中绑定的redirect_uri等数据
+// 启用PKCE时同时生成code_verifier并与state一起保存，返回对应的S256 code_challenge
+func (s *CasdoorService) generateState(ctx context.Context, entry *StateEntry) (string, string, error) {
 	// 生成32字节随机数据
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
-		return "", fmt.Errorf("failed to generate random state: %w", err)
+		return "", "", fmt.Errorf("failed to generate random state: %w", err)
 	}
 
 	state := base64.URLEncoding.EncodeToString(bytes)
 
+	// 限制未完成的state总数，防止反复请求登录URL耗尽存储
+	count, err := s.stateStore.Count(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to count states: %w", err)
+	}
+	if count >= s.appConfig.MaxStates {
+		g.Log().Warning(ctx, "Pending state limit reached:", count)
+		return "", "", ErrTooManyStates
+	}
+
 	// 存储state，设置10分钟过期时间
-	s.stateStore.mutex.Lock()
-	s.stateStore.states[state] = time.Now().Add(10 * time.Minute)
-	s.stateStore.mutex.Unlock()
+	entry.ExpiresAt = time.Now().Add(10 * time.Minute)
+
+	var codeChallenge string
+	if s.config.PKCE {
+		entry.CodeVerifier = oauth2.GenerateVerifier()
+		codeChallenge = oauth2.S256ChallengeFromVerifier(entry.CodeVerifier)
+	}
+
+	if err := s.stateStore.Save(ctx, state, entry); err != nil {
+		return "", "", fmt.Errorf("failed to store state: %w", err)
+	}
 
 	g.Log().Debug(ctx, "Generated state:", s.safeSubstring(state, 16)+"...")
-	return state, nil
+	return state, codeChallenge, nil
 }
 
-// validateState 验证state参数
-func (s *CasdoorService) validateState(ctx context.Context, state string) error {
+// validateState 验证state参数，返回state关联的数据
+func (s *CasdoorService) validateState(ctx context.Context, state string) (*StateEntry, error) {
 	if state == "" {
-		return fmt.Errorf("state parameter is empty")
+		s.stateRejected(ctx, "state parameter is empty")
+		return nil, fmt.Errorf("state parameter is empty")
 	}
 
-	s.stateStore.mutex.Lock()
-	defer s.stateStore.mutex.Unlock()
-
-	expireTime, exists := s.stateStore.states[state]
-	if !exists {
+	// 原子地取出并删除state（一次性使用，已过期的state取不到）
+	entry, err := s.stateStore.Consume(ctx, state)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to consume state:", err)
+		return nil, fmt.Errorf("failed to validate state: %w", err)
+	}
+	if entry == nil {
 		g.Log().Warning(ctx, "Invalid state parameter:", s.safeSubstring(state, 16)+"...")
-		return fmt.Errorf("invalid or expired state parameter")
+		s.stateRejected(ctx, "invalid or expired state parameter")
+		return nil, fmt.Errorf("invalid or expired state parameter")
 	}
 
-	// 检查是否过期
-	if time.Now().After(expireTime) {
-		delete(s.stateStore.states, state)
-		g.Log().Warning(ctx, "Expired state parameter:", s.safeSubstring(state, 16)+"...")
-		return fmt.Errorf("state parameter has expired")
+	g.Log().Debug(ctx, "State validation successful:", s.safeSubstring(state, 16)+"...")
+	return entry, nil
+}
+
+// stateRejected 记录state校验失败的审计事件
+func (s *CasdoorService) stateRejected(ctx context.Context, reason string) {
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventStateRejected,
+		Reason:    reason,
+	})
+}
+
+// withCodeChallenge 在授权URL上追加PKCE参数
+func (s *CasdoorService) withCodeChallenge(authURL, codeChallenge string) string {
+	if codeChallenge == "" {
+		return authURL
 	}
+	return authURL + "&code_challenge=" + url.QueryEscape(codeChallenge) + "&code_challenge_method=S256"
+}
 
-	// 使用后立即删除（一次性使用）
-	delete(s.stateStore.states, state)
-	g.Log().Debug(ctx, "State validation successful:", s.safeSubstring(state, 16)+"...")
-	return nil
+// exchangeCode 用授权码换取token，启用PKCE时携带code_verifier
+func (s *CasdoorService) exchangeCode(ctx context.Context, code, codeVerifier, redirectURI string) (*oauth2.Token, error) {
+	config := oauth2.Config{
+		ClientID:     s.config.ClientId,
+		ClientSecret: s.config.ClientSecret,
+		RedirectURL:  redirectURI,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   strings.TrimRight(s.config.Endpoint, "/") + "/api/login/oauth/authorize",
+			TokenURL:  strings.TrimRight(s.config.Endpoint, "/") + "/api/login/oauth/access_token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+
+	var options []oauth2.AuthCodeOption
+	if codeVerifier != "" {
+		options = append(options, oauth2.VerifierOption(codeVerifier))
+	}
+
+	token, err := config.Exchange(ctx, code, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Casdoor在出错时可能以"error:"前缀的access_token返回错误信息
+	if strings.HasPrefix(token.AccessToken, "error:") {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(token.AccessToken, "error:")))
+	}
+
+	return token, nil
 }
 
-// cleanupExpiredStates 清理过期的state参数
+// cleanupExpiredStates 清理过期的state参数和设备授权
 func (s *CasdoorService) cleanupExpiredStates() {
+	ctx := gctx.New()
 	ticker := time.NewTicker(5 * time.Minute) // 每5分钟清理一次
 	defer ticker.Stop()
 
 	for range ticker.C {
-		s.stateStore.mutex.Lock()
-		now := time.Now()
-		for state, expireTime := range s.stateStore.states {
-			if now.After(expireTime) {
-				delete(s.stateStore.states, state)
-			}
+		if err := s.stateStore.Cleanup(ctx); err != nil {
+			g.Log().Warning(ctx, "Failed to cleanup expired states:", err)
+		}
+		if _, err := dao.DeviceAuthorization.DeleteExpired(ctx); err != nil {
+			g.Log().Warning(ctx, "Failed to cleanup expired device authorizations:", err)
 		}
-		s.stateStore.mutex.Unlock()
 	}
 }
 
@@ -229,6 +320,36 @@ func (s *CasdoorService) loadConfig(ctx context.Context) (*CasdoorConfig, error)
 		config.ApplicationName = appName
 	}
 
+	if upstreamLogout, err := g.Cfg().Get(ctx, "casdoor.upstreamLogout"); err == nil && upstreamLogout != nil {
+		config.UpstreamLogout = upstreamLogout.Bool()
+	}
+	if upstreamLogout := os.Getenv("CASDOOR_UPSTREAM_LOGOUT"); upstreamLogout != "" {
+		config.UpstreamLogout = upstreamLogout == "true"
+	}
+
+	// PKCE默认启用，公共客户端（移动端/桌面端）同样可以安全使用授权码流程
+	config.PKCE = true
+	if pkce, err := g.Cfg().Get(ctx, "casdoor.pkce"); err == nil && pkce != nil {
+		config.PKCE = pkce.Bool()
+	}
+	if pkce := os.Getenv("CASDOOR_PKCE"); pkce != "" {
+		config.PKCE = pkce != "false"
+	}
+
+	if jwksURL, err := g.Cfg().Get(ctx, "casdoor.jwksUrl"); err == nil && !jwksURL.IsEmpty() {
+		config.JwksURL = jwksURL.String()
+	}
+	if jwksURL := os.Getenv("CASDOOR_JWKS_URL"); jwksURL != "" {
+		config.JwksURL = jwksURL
+	}
+
+	if issuer, err := g.Cfg().Get(ctx, "casdoor.issuer"); err == nil && !issuer.IsEmpty() {
+		config.Issuer = issuer.String()
+	}
+	if issuer := os.Getenv("CASDOOR_ISSUER"); issuer != "" {
+		config.Issuer = issuer
+	}
+
 	// 如果环境变量没有设置，尝试从配置文件加载
 	if config.Endpoint == "" || config.ClientId == "" {
 		cfg := g.Cfg()
@@ -329,9 +450,75 @@ func (s *CasdoorService) loadAppConfig(ctx context.Context) (*AppConfig, error)
 		config.CasdoorExternalUrl = casdoorExternalUrl
 	}
 
+	// state存储后端，多副本部署时使用redis或pgsql
+	if stateStore, err := cfg.Get(ctx, "auth.stateStore"); err == nil && stateStore != nil {
+		config.StateStore = stateStore.String()
+	} else {
+		config.StateStore = StateStoreMemory
+	}
+	if stateStore := os.Getenv("AUTH_STATE_STORE"); stateStore != "" {
+		config.StateStore = stateStore
+	}
+
+	// redirect_uri白名单：default + 当前环境的条目
+	if env, err := cfg.Get(ctx, "app.env"); err == nil && env != nil {
+		config.Environment = env.String()
+	} else {
+		config.Environment = "development"
+	}
+	if env := os.Getenv("APP_ENV"); env != "" {
+		config.Environment = env
+	}
+	if allowlist, err := cfg.Get(ctx, "auth.redirectAllowlist.default"); err == nil && allowlist != nil {
+		config.RedirectAllowlist = append(config.RedirectAllowlist, allowlist.Strings()...)
+	}
+	if allowlist, err := cfg.Get(ctx, "auth.redirectAllowlist."+config.Environment); err == nil && allowlist != nil {
+		config.RedirectAllowlist = append(config.RedirectAllowlist, allowlist.Strings()...)
+	}
+	if allowlist := os.Getenv("AUTH_REDIRECT_ALLOWLIST"); allowlist != "" {
+		config.RedirectAllowlist = strings.Split(allowlist, ",")
+	}
+	if len(config.RedirectAllowlist) == 0 {
+		config.RedirectAllowlist = defaultRedirectAllowlist(config.ExternalUrl)
+	}
+
+	// 管理员角色（Casdoor组织管理员始终视为管理员）
+	if adminRoles, err := cfg.Get(ctx, "auth.adminRoles"); err == nil && adminRoles != nil {
+		config.AdminRoles = adminRoles.Strings()
+	} else {
+		config.AdminRoles = []string{"admin"}
+	}
+	if adminRoles := os.Getenv("AUTH_ADMIN_ROLES"); adminRoles != "" {
+		config.AdminRoles = strings.Split(adminRoles, ",")
+	}
+
+	// 未完成登录的state数量上限
+	if maxStates, err := cfg.Get(ctx, "auth.maxStates"); err == nil && !maxStates.IsEmpty() {
+		config.MaxStates = maxStates.Int()
+	} else {
+		config.MaxStates = 10000
+	}
+	if maxStates, err := strconv.Atoi(os.Getenv("AUTH_MAX_STATES")); err == nil {
+		config.MaxStates = maxStates
+	}
+
+	// 是否要求邮箱已验证
+	if requireVerified, err := cfg.Get(ctx, "auth.requireVerifiedEmail"); err == nil && !requireVerified.IsEmpty() {
+		config.RequireVerifiedEmail = requireVerified.Bool()
+	}
+	if requireVerified := os.Getenv("AUTH_REQUIRE_VERIFIED_EMAIL"); requireVerified != "" {
+		config.RequireVerifiedEmail = requireVerified == "true"
+	}
+
 	g.Log().Info(ctx, "✅ 应用配置加载完成:")
 	g.Log().Info(ctx, "   - External URL:", config.ExternalUrl)
 	g.Log().Info(ctx, "   - Casdoor External URL:", config.CasdoorExternalUrl)
+	g.Log().Info(ctx, "   - State Store:", config.StateStore)
+	g.Log().Info(ctx, "   - Environment:", config.Environment)
+	g.Log().Info(ctx, "   - Redirect Allowlist:", config.RedirectAllowlist)
+	g.Log().Info(ctx, "   - Admin Roles:", config.AdminRoles)
+	g.Log().Info(ctx, "   - Max States:", config.MaxStates)
+	g.Log().Info(ctx, "   - Require Verified Email:", config.RequireVerifiedEmail)
 
 	return config, nil
 }
@@ -395,14 +582,24 @@ func (s *CasdoorService) GetLoginURL(ctx context.Context, redirectURI string) (s
 		return "", "", fmt.Errorf("redirectURI不能为空")
 	}
 
-	// 生成安全的state参数
-	state, err := s.generateState(ctx)
+	// 校验redirect_uri白名单
+	if err := s.checkRedirectURI(ctx, redirectURI); err != nil {
+		return "", "", err
+	}
+
+	return s.signinURL(ctx, &StateEntry{RedirectURI: redirectURI})
+}
+
+// signinURL 生成绑定了state（及PKCE）的Casdoor登录URL，redirect_uri取自entry
+func (s *CasdoorService) signinURL(ctx context.Context, entry *StateEntry) (string, string, error) {
+	// 生成安全的state参数（以及PKCE code_challenge）
+	state, codeChallenge, err := s.generateState(ctx, entry)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate state: %w", err)
 	}
 
 	// 使用SDK生成URL，然后替换endpoint
-	loginURL := casdoorsdk.GetSigninUrl(redirectURI)
+	loginURL := casdoorsdk.GetSigninUrl(entry.RedirectURI)
 
 	// 替换内部endpoint为外部endpoint
 	externalEndpoint := s.getExternalEndpoint()
@@ -413,6 +610,7 @@ func (s *CasdoorService) GetLoginURL(ctx context.Context, redirectURI string) (s
 	// 替换URL中的state参数为我们生成的安全state
 	// Casdoor SDK默认使用应用名称作为state
 	loginURL = strings.Replace(loginURL, "state="+s.config.ApplicationName, "state="+state, 1)
+	loginURL = s.withCodeChallenge(loginURL, codeChallenge)
 
 	g.Log().Info(ctx, "Generated secure login URL with state:", s.safeSubstring(state, 16)+"...")
 
@@ -425,6 +623,11 @@ func (s *CasdoorService) GetSignupURL(ctx context.Context, enablePassword bool,
 		return "", "", fmt.Errorf("redirectURI不能为空")
 	}
 
+	// 校验redirect_uri白名单
+	if err := s.checkRedirectURI(ctx, redirectURI); err != nil {
+		return "", "", err
+	}
+
 	var signupURL string
 	var state string
 
@@ -434,9 +637,10 @@ func (s *CasdoorService) GetSignupURL(ctx context.Context, enablePassword bool,
 		state = "" // 简化模式不需要state
 	} else {
 		// 完整OAuth2注册流程，支持注册后重定向
-		// 生成安全的state参数
+		// 生成安全的state参数（以及PKCE code_challenge）
 		var err error
-		state, err = s.generateState(ctx)
+		var codeChallenge string
+		state, codeChallenge, err = s.generateState(ctx, &StateEntry{RedirectURI: redirectURI})
 		if err != nil {
 			return "", "", fmt.Errorf("failed to generate state: %w", err)
 		}
@@ -447,6 +651,7 @@ func (s *CasdoorService) GetSignupURL(ctx context.Context, enablePassword bool,
 		// 替换URL中的state参数为我们生成的安全state
 		// 注册URL实际上是基于登录URL生成的，所以也需要替换state
 		signupURL = strings.Replace(signupURL, "state="+s.config.ApplicationName, "state="+state, 1)
+		signupURL = s.withCodeChallenge(signupURL, codeChallenge)
 	}
 
 	// 替换内部endpoint为外部endpoint
@@ -475,26 +680,66 @@ func (s *CasdoorService) GetMyProfileURL(ctx context.Context, accessToken string
 	return myProfileURL
 }
 
-// GetToken 通过授权码获取token (使用tutorial中的成功方法)
-func (s *CasdoorService) GetToken(ctx context.Context, code, state string) (string, error) {
-	token, err := casdoorsdk.GetOAuthToken(code, state)
+// ParseJwtToken 解析JWT token获取用户信息（签名公钥由KeyProvider按kid提供）
+// 除签名外还严格校验iss、aud（ClientId）、owner（组织）及exp/nbf，失败时返回*TokenError
+func (s *CasdoorService) ParseJwtToken(ctx context.Context, token string) (*casdoorsdk.Claims, error) {
+	claims, err := s.parseSignedToken(ctx, token)
 	if err != nil {
-		g.Log().Error(ctx, "Failed to get OAuth token:", err)
-		return "", err
+		return nil, err
+	}
+
+	if err := s.validateClaims(claims); err != nil {
+		g.Log().Warning(ctx, "Token claims rejected:", err, "user:", claims.User.Name)
+		return nil, err
 	}
-	return token.AccessToken, nil
+	return claims, nil
 }
 
-// ParseJwtToken 解析JWT token获取用户信息 (使用tutorial中的成功方法)
-func (s *CasdoorService) ParseJwtToken(ctx context.Context, token string) (*casdoorsdk.Claims, error) {
-	claims, err := casdoorsdk.ParseJwtToken(token)
+// parseSignedToken 只校验Casdoor令牌的签名，声明由调用方按令牌类型（用户/应用）校验
+func (s *CasdoorService) parseSignedToken(ctx context.Context, token string) (*casdoorsdk.Claims, error) {
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	parsed, err := parser.ParseWithClaims(token, &casdoorsdk.Claims{}, s.keyProvider.Keyfunc(ctx))
 	if err != nil {
 		g.Log().Error(ctx, "Failed to parse JWT token:", err)
-		return nil, err
+		return nil, newParseError(err)
+	}
+
+	claims, ok := parsed.Claims.(*casdoorsdk.Claims)
+	if !ok || !parsed.Valid {
+		return nil, &TokenError{Code: TokenErrMalformed, Message: "令牌格式错误"}
 	}
 	return claims, nil
 }
 
+// validateClaims 校验Casdoor令牌是否签发给本应用和本组织
+func (s *CasdoorService) validateClaims(claims *casdoorsdk.Claims) error {
+	err := validateRegisteredClaims(&claims.RegisteredClaims, &claimRules{
+		Issuers:   s.trustedIssuers(),
+		Audience:  s.config.ClientId,
+		ClockSkew: Token.ClockSkew(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if claims.User.Owner != s.config.OrganizationName {
+		return &TokenError{Code: TokenErrOrganization, Message: "令牌不属于本组织"}
+	}
+	return nil
+}
+
+// trustedIssuers 返回可接受的iss列表
+func (s *CasdoorService) trustedIssuers() []string {
+	if s.config.Issuer != "" {
+		return []string{s.config.Issuer}
+	}
+	issuers := []string{s.config.Endpoint, s.config.ExternalEndpoint}
+	if s.appConfig != nil {
+		issuers = append(issuers, s.appConfig.CasdoorExternalUrl)
+	}
+	return issuers
+}
+
 // GetUserInfo 获取用户信息 (使用tutorial中的成功方法)
 func (s *CasdoorService) GetUserInfo(ctx context.Context, username string) (*casdoorsdk.User, error) {
 	user, err := casdoorsdk.GetUser(username)
@@ -506,33 +751,45 @@ func (s *CasdoorService) GetUserInfo(ctx context.Context, username string) (*cas
 }
 
 // SyncUser 同步Casdoor用户到本地数据库
+// 以Casdoor的用户ID作为关联键（用户名可在Casdoor中修改），兼容尚未记录ID的旧数据时按用户名匹配
 func (s *CasdoorService) SyncUser(ctx context.Context, casdoorUser *casdoorsdk.User) (*model.User, error) {
-	// 检查用户是否已存在
-	existingUser, err := dao.User.GetByUsername(ctx, casdoorUser.Name)
-	if err != nil && err.Error() != "sql: no rows in result set" {
+	existingUser, err := s.findLocalUser(ctx, casdoorUser)
+	if err != nil {
 		return nil, err
 	}
 
 	user := &model.User{
+		CasdoorId:   casdoorUser.Id,
 		Username:    casdoorUser.Name,
 		Email:       casdoorUser.Email,
 		DisplayName: casdoorUser.DisplayName,
 		Avatar:      casdoorUser.Avatar,
 		Phone:       casdoorUser.Phone,
-		Status:      1,
+		Status:      model.UserStatusActive,
+	}
+
+	verified := s.emailVerified(casdoorUser)
+	if !verified {
+		user.Status = model.UserStatusPending
 	}
 
+	var changed []string
 	if existingUser != nil {
-		// 更新现有用户
+		changed = profileChanges(existingUser, user)
+
+		// 更新现有用户（状态由本地维护，如管理员暂停，不随Casdoor资料覆盖）
 		user.Id = existingUser.Id
+		user.Status = existingUser.Status
+		user.StatusReason = existingUser.StatusReason
+		user.StatusChangedAt = existingUser.StatusChangedAt
 		user.CreatedAt = existingUser.CreatedAt
 		user.UpdatedAt = gtime.Now()
-		err = dao.User.Update(ctx, user)
+		err = s.users.Update(ctx, user)
 	} else {
 		// 创建新用户
 		user.CreatedAt = gtime.Now()
 		user.UpdatedAt = gtime.Now()
-		err = dao.User.Create(ctx, user)
+		err = s.users.Create(ctx, user)
 	}
 
 	if err != nil {
@@ -540,148 +797,361 @@ func (s *CasdoorService) SyncUser(ctx context.Context, casdoorUser *casdoorsdk.U
 		return nil, err
 	}
 
-	g.Log().Info(ctx, "User synced successfully:", user.Username)
+	// 待验证的用户在Casdoor中完成邮箱验证后变为正常
+	if user.Status == model.UserStatusPending && verified {
+		if err := UserStatus.Transition(ctx, user, model.UserStatusActive, "email verified", model.UserStatusSourceSync); err != nil {
+			return nil, err
+		}
+	}
+
+	g.Log().Info(ctx, "User synced successfully:", user.Username, "id:", user.Id)
+
+	// 资料变更只记录变更的字段名，不记录具体值
+	if len(changed) > 0 {
+		Audit.Record(ctx, &model.AuditEvent{
+			EventType: model.AuditEventProfileUpdated,
+			UserId:    user.Id,
+			Username:  user.Username,
+			Success:   true,
+			Detail:    g.Map{"fields": changed},
+		})
+	}
 	return user, nil
 }
 
-// Login 用户登录处理
-func (s *CasdoorService) Login(ctx context.Context, code, state string) (*model.UserLoginRes, error) {
-	// 获取访问令牌
-	token, err := s.GetToken(ctx, code, state)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get token: %w", err)
+// emailVerified 是否满足邮箱验证要求（未要求邮箱已验证时始终满足）
+func (s *CasdoorService) emailVerified(casdoorUser *casdoorsdk.User) bool {
+	return !s.appConfig.RequireVerifiedEmail || casdoorUser.EmailVerified
+}
+
+// profileChanges 比较同步前后的用户资料，返回发生变更的字段
+func profileChanges(before, after *model.User) []string {
+	var changed []string
+	if before.Username != after.Username {
+		changed = append(changed, "username")
+	}
+	if before.Email != after.Email {
+		changed = append(changed, "email")
+	}
+	if before.DisplayName != after.DisplayName {
+		changed = append(changed, "displayName")
+	}
+	if before.Avatar != after.Avatar {
+		changed = append(changed, "avatar")
 	}
+	if before.Phone != after.Phone {
+		changed = append(changed, "phone")
+	}
+	return changed
+}
 
-	// 解析JWT token
-	claims, err := s.ParseJwtToken(ctx, token)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
+// findLocalUser 查找Casdoor用户对应的本地用户，不存在时返回nil
+func (s *CasdoorService) findLocalUser(ctx context.Context, casdoorUser *casdoorsdk.User) (*model.User, error) {
+	if casdoorUser.Id != "" {
+		user, err := s.users.GetByCasdoorId(ctx, casdoorUser.Id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if user != nil {
+			return user, nil
+		}
 	}
 
-	// 获取用户信息
-	casdoorUser, err := s.GetUserInfo(ctx, claims.User.Name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+	user, err := s.users.GetByUsername(ctx, casdoorUser.Name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
 	}
+	// 用户名相同但已关联其他Casdoor用户（原用户改名后名称被复用），不能合并
+	if user != nil && user.CasdoorId != "" && casdoorUser.Id != "" && user.CasdoorId != casdoorUser.Id {
+		return nil, fmt.Errorf("username %s is already bound to another casdoor user", casdoorUser.Name)
+	}
+	return user, nil
+}
 
-	// 同步用户到本地数据库
-	user, err := s.SyncUser(ctx, casdoorUser)
+// deactivateUser 将已在Casdoor中删除的本地用户变为已删除状态并软删除，使其所有令牌和会话失效
+// 与用户自行删除账号相同，用户名和邮箱随即释放；之前只改了状态的已删除用户同样会被软删除
+// source为触发删除的来源（见model.UserStatusSource常量），记录在状态变更历史和审计事件中
+func (s *CasdoorService) deactivateUser(ctx context.Context, user *model.User, reason, source string) error {
+	return Account.markDeleted(ctx, user, reason, source)
+}
+
+// deleteCasdoorUser 删除本地用户对应的Casdoor用户，Casdoor中已不存在时视为成功
+func (s *CasdoorService) deleteCasdoorUser(ctx context.Context, user *model.User) error {
+	casdoorUser, err := s.GetUserInfo(ctx, user.Username)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sync user: %w", err)
+		return fmt.Errorf("failed to get casdoor user: %w", err)
+	}
+	// 用户名已属于另一个Casdoor用户，说明原用户已被删除
+	if casdoorUser == nil || (user.CasdoorId != "" && casdoorUser.Id != user.CasdoorId) {
+		return nil
 	}
 
-	// 生成本地JWT token（可选，也可以直接使用Casdoor的token）
-	localToken, err := s.generateLocalToken(ctx, user)
+	ok, err := casdoorsdk.DeleteUser(casdoorUser)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate local token: %w", err)
+		return fmt.Errorf("failed to delete casdoor user: %w", err)
 	}
+	if !ok {
+		return fmt.Errorf("casdoor rejected the deletion of user %s", user.Username)
+	}
+	return nil
+}
 
-	return &model.UserLoginRes{
-		Token: localToken,
-		User:  user,
-	}, nil
+// revokeUserAccess 使用户的所有访问令牌、刷新令牌、个人访问令牌和登录会话失效
+func (s *CasdoorService) revokeUserAccess(ctx context.Context, user *model.User) error {
+	if err := Revocation.RevokeAllForUser(ctx, user.Username); err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	if err := dao.PersonalAccessToken.RevokeAllByUser(ctx, user.Id); err != nil {
+		return fmt.Errorf("failed to revoke personal access tokens: %w", err)
+	}
+	if err := Session.TerminateAll(ctx, user.Username); err != nil {
+		return fmt.Errorf("failed to terminate sessions: %w", err)
+	}
+	return nil
 }
 
-// generateLocalToken 生成本地JWT token
-func (s *CasdoorService) generateLocalToken(ctx context.Context, user *model.User) (string, error) {
-	// 这里可以使用GoFrame的JWT功能或者其他JWT库
-	// 为了简化，这里返回一个简单的token格式
-	tokenData := map[string]interface{}{
-		"user_id":  user.Id,
-		"username": user.Username,
-		"email":    user.Email,
-		"exp":      gtime.Now().Add(24 * 7 * time.Hour).Unix(), // 7天过期
+// localUser 获取令牌对应的本地用户，首次出现的Casdoor用户会被同步到本地
+func (s *CasdoorService) localUser(ctx context.Context, casdoorUser *casdoorsdk.User) (*model.User, error) {
+	user, err := s.findLocalUser(ctx, casdoorUser)
+	if err != nil {
+		return nil, err
 	}
+	if user != nil && user.CasdoorId == casdoorUser.Id {
+		return user, nil
+	}
+	return s.SyncUser(ctx, casdoorUser)
+}
 
-	tokenBytes, err := json.Marshal(tokenData)
+// generateLocalToken 为登录会话生成本地JWT token（由TokenService签名），会话结束后失效
+func (s *CasdoorService) generateLocalToken(ctx context.Context, user *model.User, session *model.UserSession) (string, error) {
+	token, _, err := Token.Issue(ctx, user, session.Id)
 	if err != nil {
 		return "", err
 	}
-
-	// 在实际项目中，你应该使用JWT库来生成签名的token
-	// 这里为了演示简化处理
-	return string(tokenBytes), nil
+	return token, nil
 }
 
-// VerifyToken 验证token
+// VerifyToken 验证token（支持本地签发的会话token和Casdoor token），返回本地用户
 func (s *CasdoorService) VerifyToken(ctx context.Context, token string) (*model.User, error) {
-	// 直接解析Casdoor JWT token
-	claims, err := s.ParseJwtToken(ctx, token)
+	identity, err := s.Authenticate(ctx, token)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		return nil, err
+	}
+	if identity.User == nil {
+		return nil, &TokenError{Code: TokenErrInvalid, Message: "令牌不代表用户"}
 	}
+	return identity.User, nil
+}
 
-	// 从JWT claims中构建用户信息，不需要查询本地数据库
-	user := &model.User{
-		Username:    claims.User.Name,
-		Email:       claims.User.Email,
-		DisplayName: claims.User.DisplayName,
-		Avatar:      claims.User.Avatar,
-		Phone:       claims.User.Phone,
-		Status:      1, // 默认状态为活跃
+// verifyLocalToken 用本地公钥验证本服务签发的会话token，无需访问Casdoor
+// 令牌所属的登录会话已结束时拒绝
+func (s *CasdoorService) verifyLocalToken(ctx context.Context, token string) (*model.User, *model.UserSession, error) {
+	claims, err := Token.Verify(ctx, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid local token: %w", err)
+	}
+	if err := s.checkRevoked(ctx, &claims.RegisteredClaims, token, claims.Username); err != nil {
+		return nil, nil, err
+	}
+	session, err := Session.CheckById(ctx, claims.SessionId)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return user, nil
+	user, err := s.users.GetById(ctx, claims.UserId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+	if user == nil || user.Id != session.UserId {
+		return nil, nil, fmt.Errorf("user %d not found", claims.UserId)
+	}
+	return user, session, nil
 }
 
-// UserInfo 用户信息结构体 (从tutorial中复制)
-type UserInfo struct {
-	Username    string `json:"username"`
-	DisplayName string `json:"displayName"`
-	Email       string `json:"email"`
-	Phone       string `json:"phone"`
-	Avatar      string `json:"avatar"`
+// HandleCallback 处理OAuth回调 (使用tutorial中的成功方法，添加安全验证)
+// redirectURI必须与生成state时绑定的redirect_uri一致
+// 登录成功后以Casdoor用户ID将用户同步到本地数据库，返回本地用户
+func (s *CasdoorService) HandleCallback(ctx context.Context, code, state, redirectURI string) (*model.User, *OAuthTokens, error) {
+	user, tokens, err := s.handleCallback(ctx, code, state, redirectURI)
+	if err != nil {
+		Audit.LoginFailed(ctx, "authorization_code", err)
+		return nil, nil, err
+	}
+	Audit.LoginSucceeded(ctx, user, "authorization_code")
+	return user, tokens, nil
 }
 
-// HandleCallback 处理OAuth回调 (使用tutorial中的成功方法，添加安全验证)
-func (s *CasdoorService) HandleCallback(ctx context.Context, code, state string) (*UserInfo, string, error) {
+// handleCallback 授权码登录的处理过程
+func (s *CasdoorService) handleCallback(ctx context.Context, code, state, redirectURI string) (*model.User, *OAuthTokens, error) {
 	// 1. 验证state参数（CSRF防护）
-	if err := s.validateState(ctx, state); err != nil {
+	entry, err := s.validateState(ctx, state)
+	if err != nil {
 		g.Log().Error(ctx, "State validation failed:", err)
-		return nil, "", fmt.Errorf("CSRF protection: %w", err)
+		return nil, nil, fmt.Errorf("CSRF protection: %w", err)
+	}
+
+	// 设备码登录的state只能由设备回调使用
+	if entry.DeviceUserCode != "" {
+		g.Log().Warning(ctx, "Device flow state used on browser callback")
+		return nil, nil, fmt.Errorf("CSRF protection: state belongs to a device authorization")
 	}
 
-	// 2. 获取OAuth token
-	token, err := casdoorsdk.GetOAuthToken(code, state)
+	// 校验redirect_uri与state绑定的是否一致，防止授权码被用于其他回调地址
+	if entry.RedirectURI != "" && entry.RedirectURI != redirectURI {
+		g.Log().Warning(ctx, "redirect_uri mismatch for state, expected:", entry.RedirectURI, "got:", redirectURI)
+		return nil, nil, &RedirectURIError{Code: RedirectErrMismatch, RedirectURI: redirectURI, Reason: "redirect_uri与state不匹配"}
+	}
+
+	// 2. 获取OAuth token（启用PKCE时携带state关联的code_verifier）
+	token, err := s.exchangeCode(ctx, code, entry.CodeVerifier, entry.RedirectURI)
 	if err != nil {
 		g.Log().Error(ctx, "Failed to get OAuth token:", err)
-		return nil, "", err
+		return nil, nil, err
 	}
 
 	// 解析JWT token获取用户信息
-	claims, err := casdoorsdk.ParseJwtToken(token.AccessToken)
+	claims, err := s.ParseJwtToken(ctx, token.AccessToken)
 	if err != nil {
-		g.Log().Error(ctx, "Failed to parse JWT token:", err)
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// 同步用户到本地数据库
+	user, err := s.SyncUser(ctx, &claims.User)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sync user: %w", err)
+	}
+
+	// 已暂停、已删除或待验证的用户不签发令牌
+	if err := CheckUserStatus(user); err != nil {
+		return nil, nil, err
+	}
+
+	tokens := &OAuthTokens{
+		AccessToken: token.AccessToken,
+		ExpiresAt:   token.Expiry,
 	}
 
-	// 转换为我们的用户信息格式
-	userInfo := &UserInfo{
-		Username:    claims.User.Name,
-		DisplayName: claims.User.DisplayName,
-		Email:       claims.User.Email,
-		Phone:       claims.User.Phone,
-		Avatar:      claims.User.Avatar,
+	// 3. 保存Casdoor的refresh token，并签发本服务的轮换刷新令牌
+	var familyId string
+	if token.RefreshToken != "" {
+		familyId = guid.S()
+		tokens.RefreshToken, err = s.issueRefreshToken(ctx, claims.User.Name, familyId, 0, token.RefreshToken)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to issue refresh token: %w", err)
+		}
+	}
+
+	// 4. 记录登录会话，用户可查看并结束自己的会话
+	session, err := Session.Record(ctx, user, &claims.RegisteredClaims, token.AccessToken, familyId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to record session: %w", err)
+	}
+
+	// 5. 签发属于该会话的本地会话令牌
+	tokens.SessionToken, err = s.generateLocalToken(ctx, user, session)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue local session token: %w", err)
 	}
 
-	return userInfo, token.AccessToken, nil
+	return user, tokens, nil
 }
 
-// ValidateToken 验证token (使用tutorial中的成功方法)
-func (s *CasdoorService) ValidateToken(ctx context.Context, token string) (*UserInfo, error) {
-	claims, err := casdoorsdk.ParseJwtToken(token)
+// checkRevoked 检查令牌是否已被吊销（登出后的令牌在过期前不能继续使用）
+func (s *CasdoorService) checkRevoked(ctx context.Context, claims *jwt.RegisteredClaims, token, username string) error {
+	revoked, err := Revocation.IsRevoked(ctx, claims, token, username)
 	if err != nil {
-		g.Log().Error(ctx, "Failed to validate token:", err)
-		return nil, err
+		g.Log().Error(ctx, "Failed to check token revocation:", err)
+		return fmt.Errorf("failed to check token revocation: %w", err)
 	}
+	if revoked {
+		g.Log().Warning(ctx, "Revoked token rejected, user:", username)
+		return ErrTokenRevoked
+	}
+	return nil
+}
 
-	userInfo := &UserInfo{
-		Username:    claims.User.Name,
-		DisplayName: claims.User.DisplayName,
-		Email:       claims.User.Email,
-		Phone:       claims.User.Phone,
-		Avatar:      claims.User.Avatar,
+// Logout 登出：吊销当前访问令牌及其刷新令牌；allSessions为true时吊销该用户的所有令牌
+func (s *CasdoorService) Logout(ctx context.Context, token, refreshToken string, allSessions bool) error {
+	// 个人访问令牌不是会话，需通过令牌管理接口吊销
+	if PersonalToken.IsPersonalToken(token) {
+		return ErrPersonalTokenLogout
 	}
 
-	return userInfo, nil
+	var (
+		registered *jwt.RegisteredClaims
+		username   string
+		local      *LocalClaims
+		isLocal    = Token.IsLocalToken(token)
+	)
+
+	if isLocal {
+		claims, err := Token.Verify(ctx, token)
+		if err != nil {
+			return fmt.Errorf("invalid local token: %w", err)
+		}
+		registered, username, local = &claims.RegisteredClaims, claims.Username, claims
+	} else {
+		claims, err := s.ParseJwtToken(ctx, token)
+		if err != nil {
+			return fmt.Errorf("invalid token: %w", err)
+		}
+		registered, username = &claims.RegisteredClaims, claims.User.Name
+	}
+
+	if err := Revocation.RevokeToken(ctx, registered, token, username); err != nil {
+		return err
+	}
+
+	if allSessions {
+		if err := Revocation.RevokeAllForUser(ctx, username); err != nil {
+			return err
+		}
+		if err := Session.TerminateAll(ctx, username); err != nil {
+			return err
+		}
+	} else if local != nil {
+		// 本地会话令牌通过sid关联会话，结束会话同时吊销其刷新令牌
+		if err := Session.Terminate(ctx, local.UserId, local.SessionId); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+	} else {
+		if err := Session.TerminateByToken(ctx, registered, token); err != nil {
+			return err
+		}
+		if refreshToken != "" {
+			s.revokeRefreshToken(ctx, refreshToken)
+		}
+	}
+
+	// 可选：通知Casdoor结束其会话（失败不影响本地登出）
+	if s.config.UpstreamLogout && !isLocal {
+		s.upstreamLogout(ctx, token)
+	}
+
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventLogout,
+		Username:  username,
+		Success:   true,
+		Detail:    g.Map{"allSessions": allSessions},
+	})
+
+	g.Log().Info(ctx, "User logged out:", username, "allSessions:", allSessions)
+	return nil
+}
+
+// upstreamLogout 调用Casdoor的登出接口
+func (s *CasdoorService) upstreamLogout(ctx context.Context, token string) {
+	logoutURL := strings.TrimRight(s.config.Endpoint, "/") + "/api/logout"
+	response, err := g.Client().Get(ctx, logoutURL, g.Map{
+		"id_token_hint": token,
+	})
+	if err != nil {
+		g.Log().Warning(ctx, "Casdoor logout request failed:", err)
+		return
+	}
+	defer response.Close()
+
+	if response.StatusCode != 200 {
+		g.Log().Warning(ctx, "Casdoor logout returned status:", response.StatusCode)
+	}
 }
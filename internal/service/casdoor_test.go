@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+)
+
+// TestPKCEExchange 登录URL携带S256 code_challenge，换取token时发送与之对应的code_verifier
+func TestPKCEExchange(t *testing.T) {
+	ctx := context.Background()
+	const redirectURI = "https://app.example.com/callback"
+
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/login/oauth/access_token" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	defer server.Close()
+
+	casdoorsdk.InitConfig(server.URL, "client-id", "client-secret", "", "org", "app")
+	s := &CasdoorService{
+		config: &CasdoorConfig{
+			Endpoint:         server.URL,
+			ExternalEndpoint: server.URL,
+			ClientId:         "client-id",
+			ClientSecret:     "client-secret",
+			OrganizationName: "org",
+			ApplicationName:  "app",
+			PKCE:             true,
+		},
+		appConfig:  &AppConfig{MaxStates: 10},
+		stateStore: newMemoryStateStore(),
+	}
+
+	loginURL, state, err := s.signinURL(ctx, &StateEntry{RedirectURI: redirectURI})
+	if err != nil {
+		t.Fatalf("signinURL: %v", err)
+	}
+	parsed, err := url.Parse(loginURL)
+	if err != nil {
+		t.Fatalf("parse login URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("state") != state {
+		t.Fatalf("login URL state: got %q, want %q", query.Get("state"), state)
+	}
+	if method := query.Get("code_challenge_method"); method != "S256" {
+		t.Fatalf("code_challenge_method: got %q, want S256", method)
+	}
+	challenge := query.Get("code_challenge")
+	if challenge == "" {
+		t.Fatal("login URL has no code_challenge")
+	}
+
+	entry, err := s.validateState(ctx, state)
+	if err != nil {
+		t.Fatalf("validateState: %v", err)
+	}
+	token, err := s.exchangeCode(ctx, "auth-code", entry.CodeVerifier, entry.RedirectURI)
+	if err != nil {
+		t.Fatalf("exchangeCode: %v", err)
+	}
+	if token.AccessToken != "access-token" {
+		t.Fatalf("access token: got %q", token.AccessToken)
+	}
+
+	verifier := form.Get("code_verifier")
+	if verifier == "" {
+		t.Fatal("token request has no code_verifier")
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if got := base64.RawURLEncoding.EncodeToString(sum[:]); got != challenge {
+		t.Fatalf("S256(code_verifier) = %q, want code_challenge %q", got, challenge)
+	}
+	if form.Get("code") != "auth-code" || form.Get("redirect_uri") != redirectURI {
+		t.Fatalf("token request: got code %q, redirect_uri %q", form.Get("code"), form.Get("redirect_uri"))
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 令牌校验失败的错误码，客户端可据此区分"过期"与"受众错误"等情况
+const (
+	TokenErrMalformed         = "token_malformed"
+	TokenErrInvalid           = "token_invalid"
+	TokenErrSignature         = "token_signature_invalid"
+	TokenErrExpired           = "token_expired"
+	TokenErrNotYetValid       = "token_not_yet_valid"
+	TokenErrIssuer            = "token_invalid_issuer"
+	TokenErrAudience          = "token_invalid_audience"
+	TokenErrOrganization      = "token_invalid_organization"
+	TokenErrRevoked           = "token_revoked"
+	TokenErrSessionTerminated = "session_terminated"
+)
+
+// TokenError 令牌校验失败
+type TokenError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *TokenError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// AsTokenError 从错误链中提取TokenError，非令牌校验错误（如数据库故障）返回nil
+func AsTokenError(err error) *TokenError {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return tokenErr
+	}
+	return nil
+}
+
+// ErrTokenRevoked 令牌已被吊销（登出或登出所有会话）
+var ErrTokenRevoked = &TokenError{Code: TokenErrRevoked, Message: "令牌已被吊销"}
+
+// newParseError 将jwt解析错误转换为TokenError
+func newParseError(err error) *TokenError {
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) {
+		return tokenErr
+	}
+
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorMalformed != 0 {
+		return &TokenError{Code: TokenErrMalformed, Message: "令牌格式错误", Err: err}
+	}
+	return &TokenError{Code: TokenErrSignature, Message: "令牌签名无效", Err: err}
+}
+
+// claimRules 注册声明的校验规则
+type claimRules struct {
+	Issuers   []string      // 允许的iss（任意一个匹配即可）
+	Audience  string        // aud必须包含的值
+	ClockSkew time.Duration // 时间校验允许的时钟偏差
+}
+
+// validateRegisteredClaims 严格校验exp/nbf/iat/iss/aud
+func validateRegisteredClaims(claims *jwt.RegisteredClaims, rules *claimRules) error {
+	now := time.Now()
+
+	if claims.ExpiresAt == nil {
+		return &TokenError{Code: TokenErrExpired, Message: "令牌缺少过期时间"}
+	}
+	if now.After(claims.ExpiresAt.Add(rules.ClockSkew)) {
+		return &TokenError{Code: TokenErrExpired, Message: "令牌已过期"}
+	}
+	if claims.NotBefore != nil && now.Add(rules.ClockSkew).Before(claims.NotBefore.Time) {
+		return &TokenError{Code: TokenErrNotYetValid, Message: "令牌尚未生效"}
+	}
+	if claims.IssuedAt != nil && now.Add(rules.ClockSkew).Before(claims.IssuedAt.Time) {
+		return &TokenError{Code: TokenErrNotYetValid, Message: "令牌签发时间无效"}
+	}
+
+	issuerMatched := false
+	for _, issuer := range rules.Issuers {
+		if issuer != "" && strings.TrimRight(claims.Issuer, "/") == strings.TrimRight(issuer, "/") {
+			issuerMatched = true
+			break
+		}
+	}
+	if !issuerMatched {
+		return &TokenError{Code: TokenErrIssuer, Message: "令牌签发者不受信任"}
+	}
+
+	if !claims.VerifyAudience(rules.Audience, true) {
+		return &TokenError{Code: TokenErrAudience, Message: "令牌不是签发给本应用的"}
+	}
+
+	return nil
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/guid"
+)
+
+const (
+	// deviceCodeTTL 设备码有效期
+	deviceCodeTTL = 10 * time.Minute
+	// devicePollInterval 默认轮询间隔（秒）
+	devicePollInterval = 5
+	// deviceSlowDownStep 轮询过快时增加的间隔（秒），RFC 8628 3.5
+	deviceSlowDownStep = 5
+	// deviceUserCodeAlphabet 用户码字符集（去掉元音和易混淆字符）
+	deviceUserCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	// DeviceGrantType 设备码轮询使用的grant_type
+	DeviceGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+// 设备码轮询的错误（与RFC 8628 3.5的错误码对应）
+var (
+	ErrDeviceAuthorizationPending = errors.New("authorization_pending")
+	ErrDeviceSlowDown             = errors.New("slow_down")
+	ErrDeviceAccessDenied         = errors.New("access_denied")
+	ErrDeviceExpiredToken         = errors.New("expired_token")
+	ErrDeviceInvalidGrant         = errors.New("invalid_grant")
+)
+
+// ErrDeviceVerificationFailed 确认设备授权的CSRF令牌无效，或与发起确认的浏览器不一致
+var ErrDeviceVerificationFailed = errors.New("device verification failed")
+
+// DeviceCodeResponse 设备授权响应（RFC 8628 3.2）
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceConfirmation 浏览器确认设备授权时展示的信息（RFC 8628 5.4：让用户核对用户码和发起请求的客户端）
+type DeviceConfirmation struct {
+	UserCode  string `json:"user_code"`
+	ClientIp  string `json:"client_ip"`
+	UserAgent string `json:"user_agent"`
+	ExpiresIn int    `json:"expires_in"`
+	CSRFToken string `json:"csrf_token"` // 确认时随表单提交，只能由确认页读取
+}
+
+// StartDeviceAuthorization 为CLI创建设备授权：返回device_code（CLI轮询用）和user_code（用户在浏览器中输入）
+func (s *CasdoorService) StartDeviceAuthorization(ctx context.Context) (*DeviceCodeResponse, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+	deviceCode := base64.RawURLEncoding.EncodeToString(bytes)
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := &model.DeviceAuthorization{
+		DeviceCodeHash: hashRefreshToken(deviceCode),
+		UserCode:       userCode,
+		Status:         model.DeviceStatusPending,
+		PollInterval:   devicePollInterval,
+		ExpiresAt:      gtime.Now().Add(deviceCodeTTL),
+		CreatedAt:      gtime.Now(),
+	}
+	if r := g.RequestFromCtx(ctx); r != nil {
+		authorization.ClientIp = r.GetClientIp()
+		authorization.UserAgent = truncate(r.UserAgent(), 500)
+	}
+	if err := dao.DeviceAuthorization.Create(ctx, authorization); err != nil {
+		g.Log().Error(ctx, "Failed to create device authorization:", err)
+		return nil, err
+	}
+
+	verificationURI := strings.TrimRight(s.appConfig.ExternalUrl, "/") + "/device"
+	g.Log().Info(ctx, "Device authorization started, user code:", userCode)
+
+	return &DeviceCodeResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(deviceCodeTTL.Seconds()),
+		Interval:                devicePollInterval,
+	}, nil
+}
+
+// generateUserCode 生成形如BCDF-GHJK的用户码
+func generateUserCode() (string, error) {
+	code := make([]byte, 0, 9)
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(deviceUserCodeAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate user code: %w", err)
+		}
+		code = append(code, deviceUserCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode 统一用户码格式（忽略大小写、空格和连字符）
+func normalizeUserCode(userCode string) string {
+	code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(userCode))
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// deviceCallbackURL 设备码登录在Casdoor完成后的回调地址（需在Casdoor应用的回调地址中登记）
+func (s *CasdoorService) deviceCallbackURL() string {
+	return strings.TrimRight(s.appConfig.ExternalUrl, "/") + "/api/v1/auth/device/callback"
+}
+
+// ConfirmDeviceAuthorization 用户输入用户码后返回确认页展示的信息，并生成绑定当前浏览器的随机值（由调用方写入HttpOnly Cookie）
+// 确认页的CSRF令牌与该浏览器绑定，其他页面无法替用户提交确认
+func (s *CasdoorService) ConfirmDeviceAuthorization(ctx context.Context, userCode string) (*DeviceConfirmation, string, error) {
+	authorization, err := s.pendingDeviceAuthorization(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		return nil, "", err
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate device binding: %w", err)
+	}
+	binding := base64.RawURLEncoding.EncodeToString(bytes)
+
+	csrfToken, _, err := s.generateState(ctx, &StateEntry{
+		DeviceUserCode: authorization.UserCode,
+		DeviceBinding:  hashRefreshToken(binding),
+		DeviceConfirm:  true,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &DeviceConfirmation{
+		UserCode:  authorization.UserCode,
+		ClientIp:  authorization.ClientIp,
+		UserAgent: authorization.UserAgent,
+		ExpiresIn: int(authorization.ExpiresAt.Sub(gtime.Now()).Seconds()),
+		CSRFToken: csrfToken,
+	}, binding, nil
+}
+
+// GetDeviceLoginURL 用户在确认页确认后，校验CSRF令牌和浏览器绑定，生成绑定该设备授权的Casdoor登录URL
+func (s *CasdoorService) GetDeviceLoginURL(ctx context.Context, userCode, csrfToken, binding string) (string, error) {
+	entry, err := s.validateState(ctx, csrfToken)
+	if err != nil {
+		g.Log().Warning(ctx, "Device confirmation rejected:", err)
+		return "", ErrDeviceVerificationFailed
+	}
+	if !entry.DeviceConfirm || entry.DeviceUserCode != normalizeUserCode(userCode) || !deviceBindingMatches(entry, binding) {
+		g.Log().Warning(ctx, "Device confirmation does not match the browser, user code:", entry.DeviceUserCode)
+		return "", ErrDeviceVerificationFailed
+	}
+
+	authorization, err := s.pendingDeviceAuthorization(ctx, entry.DeviceUserCode)
+	if err != nil {
+		return "", err
+	}
+
+	// 复用登录流程的state（及PKCE），state中记录用户码和浏览器绑定，回调时据此批准设备授权
+	loginURL, _, err := s.signinURL(ctx, &StateEntry{
+		RedirectURI:    s.deviceCallbackURL(),
+		DeviceUserCode: authorization.UserCode,
+		DeviceBinding:  entry.DeviceBinding,
+	})
+	if err != nil {
+		return "", err
+	}
+	return loginURL, nil
+}
+
+// deviceBindingMatches 校验请求中的浏览器绑定值与state中保存的摘要一致
+func deviceBindingMatches(entry *StateEntry, binding string) bool {
+	if entry.DeviceBinding == "" || binding == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashRefreshToken(binding)), []byte(entry.DeviceBinding)) == 1
+}
+
+// pendingDeviceAuthorization 获取待授权的设备授权记录
+func (s *CasdoorService) pendingDeviceAuthorization(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	authorization, err := dao.DeviceAuthorization.GetByUserCode(ctx, userCode)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if authorization == nil || authorization.Status != model.DeviceStatusPending {
+		return nil, ErrDeviceInvalidGrant
+	}
+	if authorization.ExpiresAt.Before(gtime.Now()) {
+		return nil, ErrDeviceExpiredToken
+	}
+	return authorization, nil
+}
+
+// HandleDeviceCallback 处理设备码登录的Casdoor回调：换取令牌、同步用户并批准设备授权
+// code为空表示用户在Casdoor中取消了登录；binding为确认设备授权时写入浏览器的Cookie值
+func (s *CasdoorService) HandleDeviceCallback(ctx context.Context, code, state, binding string) error {
+	user, err := s.handleDeviceCallback(ctx, code, state, binding)
+	if err != nil {
+		Audit.LoginFailed(ctx, "device_code", err)
+		return err
+	}
+	Audit.LoginSucceeded(ctx, user, "device_code")
+	return nil
+}
+
+// handleDeviceCallback 设备码登录回调的处理过程，返回批准授权的用户
+func (s *CasdoorService) handleDeviceCallback(ctx context.Context, code, state, binding string) (*model.User, error) {
+	entry, err := s.validateState(ctx, state)
+	if err != nil {
+		g.Log().Error(ctx, "Device state validation failed:", err)
+		return nil, fmt.Errorf("CSRF protection: %w", err)
+	}
+	if entry.DeviceUserCode == "" || entry.DeviceConfirm {
+		return nil, fmt.Errorf("CSRF protection: state does not belong to a device authorization")
+	}
+	// 回调必须回到确认授权的浏览器，防止把别人的登录链接转发给用户完成授权
+	if !deviceBindingMatches(entry, binding) {
+		return nil, fmt.Errorf("CSRF protection: device authorization was confirmed in another browser")
+	}
+
+	if code == "" {
+		if err := dao.DeviceAuthorization.Deny(ctx, entry.DeviceUserCode); err != nil {
+			return nil, err
+		}
+		g.Log().Info(ctx, "Device authorization denied, user code:", entry.DeviceUserCode)
+		return nil, ErrDeviceAccessDenied
+	}
+
+	token, err := s.exchangeCode(ctx, code, entry.CodeVerifier, entry.RedirectURI)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to get OAuth token for device:", err)
+		return nil, err
+	}
+
+	claims, err := s.ParseJwtToken(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.SyncUser(ctx, &claims.User)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync user: %w", err)
+	}
+	// 不能登录的用户直接拒绝授权，命令行轮询时得到access_denied
+	if statusErr := CheckUserStatus(user); statusErr != nil {
+		if err := dao.DeviceAuthorization.Deny(ctx, entry.DeviceUserCode); err != nil {
+			return nil, err
+		}
+		return nil, statusErr
+	}
+
+	var refreshToken, familyId string
+	if token.RefreshToken != "" {
+		familyId = guid.S()
+		refreshToken, err = s.issueRefreshToken(ctx, user.Username, familyId, 0, token.RefreshToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to issue refresh token: %w", err)
+		}
+	}
+
+	// 设备码登录同样记录会话（在交给CLI之前），用户可在会话列表中看到并结束
+	if _, err := Session.Record(ctx, user, &claims.RegisteredClaims, token.AccessToken, familyId); err != nil {
+		return nil, fmt.Errorf("failed to record session: %w", err)
+	}
+
+	var tokenExpiresAt *gtime.Time
+	if !token.Expiry.IsZero() {
+		tokenExpiresAt = gtime.New(token.Expiry)
+	}
+	approved, err := dao.DeviceAuthorization.Approve(ctx, entry.DeviceUserCode, user.Username, token.AccessToken, refreshToken, tokenExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !approved {
+		if err := Session.TerminateByToken(ctx, &claims.RegisteredClaims, token.AccessToken); err != nil {
+			g.Log().Warning(ctx, "Failed to terminate session of expired device authorization:", err)
+		}
+		return nil, ErrDeviceExpiredToken
+	}
+
+	g.Log().Info(ctx, "Device authorization approved, user:", user.Username, "user code:", entry.DeviceUserCode)
+	return user, nil
+}
+
+// PollDeviceToken CLI轮询设备授权结果（RFC 8628 3.4/3.5）
+// 令牌只会返回一次，返回后立即删除设备授权记录
+func (s *CasdoorService) PollDeviceToken(ctx context.Context, deviceCode string) (*OAuthTokens, error) {
+	authorization, err := dao.DeviceAuthorization.GetByDeviceCodeHash(ctx, hashRefreshToken(deviceCode))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if authorization == nil {
+		return nil, ErrDeviceInvalidGrant
+	}
+	if authorization.ExpiresAt.Before(gtime.Now()) {
+		return nil, ErrDeviceExpiredToken
+	}
+
+	switch authorization.Status {
+	case model.DeviceStatusDenied:
+		if _, err := dao.DeviceAuthorization.Delete(ctx, authorization.Id); err != nil {
+			g.Log().Warning(ctx, "Failed to delete denied device authorization:", err)
+		}
+		return nil, ErrDeviceAccessDenied
+
+	case model.DeviceStatusApproved:
+		// 删除成功的请求才能取得令牌，防止并发轮询重复取走
+		deleted, err := dao.DeviceAuthorization.Delete(ctx, authorization.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !deleted {
+			return nil, ErrDeviceInvalidGrant
+		}
+
+		tokens := &OAuthTokens{
+			AccessToken:  authorization.AccessToken,
+			RefreshToken: authorization.RefreshToken,
+		}
+		if authorization.TokenExpiresAt != nil {
+			tokens.ExpiresAt = authorization.TokenExpiresAt.Time
+		}
+		g.Log().Info(ctx, "Device tokens delivered, user:", authorization.Username)
+		return tokens, nil
+	}
+
+	// 仍在等待用户登录：轮询过快时要求客户端放慢（每次增加5秒）
+	interval := authorization.PollInterval
+	tooFast := authorization.LastPolledAt != nil &&
+		gtime.Now().Sub(authorization.LastPolledAt) < time.Duration(interval)*time.Second
+	if tooFast {
+		interval += deviceSlowDownStep
+	}
+	if err := dao.DeviceAuthorization.UpdatePoll(ctx, authorization.Id, interval); err != nil {
+		return nil, err
+	}
+
+	if tooFast {
+		return nil, ErrDeviceSlowDown
+	}
+	return nil, ErrDeviceAuthorizationPending
+}
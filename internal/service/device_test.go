@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newDeviceTestService 只带内存state存储的CasdoorService，用于不访问数据库的设备码校验测试
+func newDeviceTestService() *CasdoorService {
+	return &CasdoorService{
+		config:     &CasdoorConfig{},
+		appConfig:  &AppConfig{MaxStates: 10, ExternalUrl: "https://id.example.com"},
+		stateStore: newMemoryStateStore(),
+	}
+}
+
+// TestDeviceLoginURLRequiresBinding 确认设备授权时，CSRF令牌必须来自同一浏览器且用户码一致
+func TestDeviceLoginURLRequiresBinding(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name     string
+		entry    *StateEntry
+		userCode string
+		binding  string
+	}{
+		{"other browser", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser"), DeviceConfirm: true}, "BCDF-GHJK", "attacker"},
+		{"missing cookie", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser"), DeviceConfirm: true}, "BCDF-GHJK", ""},
+		{"other user code", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser"), DeviceConfirm: true}, "LMNP-QRST", "browser"},
+		{"login state", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser")}, "BCDF-GHJK", "browser"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newDeviceTestService()
+			csrfToken, _, err := s.generateState(ctx, tc.entry)
+			if err != nil {
+				t.Fatalf("generateState: %v", err)
+			}
+			if _, err := s.GetDeviceLoginURL(ctx, tc.userCode, csrfToken, tc.binding); !errors.Is(err, ErrDeviceVerificationFailed) {
+				t.Fatalf("GetDeviceLoginURL: got %v, want ErrDeviceVerificationFailed", err)
+			}
+		})
+	}
+}
+
+// TestDeviceCallbackRequiresBinding 设备码登录回调必须回到确认授权的浏览器，确认页的CSRF令牌不能当作state使用
+func TestDeviceCallbackRequiresBinding(t *testing.T) {
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		entry   *StateEntry
+		binding string
+		want    string
+	}{
+		{"other browser", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser")}, "attacker", "another browser"},
+		{"missing cookie", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser")}, "", "another browser"},
+		{"confirmation token", &StateEntry{DeviceUserCode: "BCDF-GHJK", DeviceBinding: hashRefreshToken("browser"), DeviceConfirm: true}, "browser", "does not belong"},
+		{"login state", &StateEntry{RedirectURI: "https://app.example.com/callback"}, "browser", "does not belong"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newDeviceTestService()
+			state, _, err := s.generateState(ctx, tc.entry)
+			if err != nil {
+				t.Fatalf("generateState: %v", err)
+			}
+			_, err = s.handleDeviceCallback(ctx, "auth-code", state, tc.binding)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("handleDeviceCallback: got %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+}
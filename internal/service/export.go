@@ -0,0 +1,357 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+const (
+	// exportPageSize 导出审计事件时每次查询的数量
+	exportPageSize = 100
+	// exportStaleAfter 超过该时间仍未完成的导出视为生成进程已退出
+	exportStaleAfter = time.Hour
+	// exportCleanupInterval 清理过期导出的间隔
+	exportCleanupInterval = time.Hour
+)
+
+var (
+	// ErrExportNotFound 导出不存在、下载令牌错误或链接已过期
+	ErrExportNotFound = errors.New("data export not found or expired")
+	// ErrExportNotReady 导出仍在生成中或生成失败
+	ErrExportNotReady = errors.New("data export is not ready")
+	// ErrExportInProgress 用户已有正在生成的导出
+	ErrExportInProgress = errors.New("a data export is already in progress")
+)
+
+// ExportConfig 个人数据导出配置结构体
+type ExportConfig struct {
+	SyncMaxRecords int           // 记录数不超过该值时直接返回导出包，超过时异步生成
+	LinkTTL        time.Duration // 异步导出下载链接的有效期
+}
+
+// ExportService 个人数据导出：将本服务保存的用户数据打包为zip（data.json和summary.txt）
+type ExportService struct {
+	config *ExportConfig
+}
+
+var Export = &ExportService{}
+
+// Init 加载导出配置，并启动过期导出的清理任务
+func (s *ExportService) Init(ctx context.Context) error {
+	config := &ExportConfig{
+		SyncMaxRecords: 1000,
+		LinkTTL:        24 * time.Hour,
+	}
+	cfg := g.Cfg()
+
+	if syncMax, err := cfg.Get(ctx, "export.syncMaxRecords"); err == nil && !syncMax.IsEmpty() {
+		config.SyncMaxRecords = syncMax.Int()
+	}
+	if linkTTL, err := cfg.Get(ctx, "export.linkTtl"); err == nil && !linkTTL.IsEmpty() {
+		config.LinkTTL = linkTTL.Duration()
+	}
+
+	// 环境变量覆盖配置文件
+	if linkTTL := os.Getenv("EXPORT_LINK_TTL"); linkTTL != "" {
+		duration, err := time.ParseDuration(linkTTL)
+		if err != nil {
+			return fmt.Errorf("EXPORT_LINK_TTL 格式错误: %w", err)
+		}
+		config.LinkTTL = duration
+	}
+
+	if config.LinkTTL <= 0 {
+		return fmt.Errorf("export.linkTtl 必须大于0")
+	}
+
+	s.config = config
+	go s.cleanupExpired()
+
+	g.Log().Info(ctx, "✅ 个人数据导出配置加载完成:")
+	g.Log().Info(ctx, "   - Sync Max Records:", config.SyncMaxRecords)
+	g.Log().Info(ctx, "   - Link TTL:", config.LinkTTL)
+	return nil
+}
+
+// NeedsAsync 数据量较大（审计事件超过syncMaxRecords）时需要异步生成
+func (s *ExportService) NeedsAsync(ctx context.Context, user *model.User) (bool, error) {
+	_, total, err := dao.AuditEvent.List(ctx, &model.AuditEventListReq{UserId: user.Id, Page: 1, PageSize: 1})
+	if err != nil {
+		return false, err
+	}
+	return total > s.config.SyncMaxRecords, nil
+}
+
+// Build 同步生成用户的导出包
+func (s *ExportService) Build(ctx context.Context, user *model.User) ([]byte, error) {
+	archive, err := s.build(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	s.recordExported(ctx, user, "sync", nil)
+	return archive, nil
+}
+
+// Start 创建异步导出，返回导出记录和下载令牌（令牌只在此时返回一次）
+func (s *ExportService) Start(ctx context.Context, user *model.User) (*model.DataExport, string, error) {
+	pending, err := dao.DataExport.HasPending(ctx, user.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	if pending {
+		return nil, "", ErrExportInProgress
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate download token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	export := &model.DataExport{
+		UserId:    user.Id,
+		Status:    model.DataExportPending,
+		TokenHash: hashDownloadToken(token),
+		CreatedAt: gtime.Now(),
+	}
+	if err := dao.DataExport.Create(ctx, export); err != nil {
+		return nil, "", fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	// 生成过程不依赖请求上下文，请求结束后继续执行
+	go s.generate(gctx.New(), export.Id, user)
+
+	s.recordExported(ctx, user, "async", g.Map{"exportId": export.Id})
+	return export, token, nil
+}
+
+// Get 获取用户自己的导出记录
+func (s *ExportService) Get(ctx context.Context, userId, id uint64) (*model.DataExport, error) {
+	export, err := dao.DataExport.GetByUser(ctx, userId, id)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, ErrExportNotFound
+	}
+	return export, nil
+}
+
+// Download 校验下载令牌并返回导出包
+func (s *ExportService) Download(ctx context.Context, id uint64, token string) (*model.DataExport, error) {
+	if token == "" {
+		return nil, ErrExportNotFound
+	}
+	tokenHash := hashDownloadToken(token)
+	export, err := dao.DataExport.GetByHash(ctx, id, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		return nil, ErrExportNotFound
+	}
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(gtime.Now()) {
+		return nil, ErrExportNotFound
+	}
+	if export.Status != model.DataExportReady {
+		return nil, ErrExportNotReady
+	}
+
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventExportDownloaded,
+		UserId:    export.UserId,
+		Success:   true,
+		Detail:    g.Map{"exportId": export.Id, "size": export.Size},
+	})
+	return export, nil
+}
+
+// generate 异步生成导出包并保存
+func (s *ExportService) generate(ctx context.Context, id uint64, user *model.User) {
+	archive, err := s.build(ctx, user)
+	expiresAt := gtime.Now().Add(s.config.LinkTTL)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to build data export:", id, err)
+		if err := dao.DataExport.Fail(ctx, id, "failed to collect user data", expiresAt); err != nil {
+			g.Log().Error(ctx, "Failed to mark data export as failed:", id, err)
+		}
+		return
+	}
+
+	if err := dao.DataExport.Complete(ctx, id, archive, expiresAt); err != nil {
+		g.Log().Error(ctx, "Failed to save data export:", id, err)
+		return
+	}
+	g.Log().Info(ctx, "Data export ready:", id, "user:", user.Username, "size:", len(archive))
+}
+
+// build 收集用户数据并打包
+func (s *ExportService) build(ctx context.Context, user *model.User) ([]byte, error) {
+	data, err := s.collect(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return s.archive(data)
+}
+
+// collect 收集本服务保存的与用户相关的全部数据
+func (s *ExportService) collect(ctx context.Context, user *model.User) (*model.UserDataExport, error) {
+	data := &model.UserDataExport{ExportedAt: gtime.Now(), User: user}
+
+	var err error
+	if data.StatusHistory, err = dao.UserStatusHistory.ListByUser(ctx, user.Id); err != nil {
+		return nil, fmt.Errorf("failed to list status history: %w", err)
+	}
+	if data.Sessions, err = dao.UserSession.ListByUser(ctx, user.Id); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if data.PersonalAccessTokens, err = dao.PersonalAccessToken.ListByUser(ctx, user.Id); err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+	if data.RefreshTokens, err = dao.RefreshToken.ListByUsername(ctx, user.Username); err != nil {
+		return nil, fmt.Errorf("failed to list refresh tokens: %w", err)
+	}
+
+	for page := 1; ; page++ {
+		events, total, err := dao.AuditEvent.List(ctx, &model.AuditEventListReq{UserId: user.Id, Page: page, PageSize: exportPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit events: %w", err)
+		}
+		data.AuditEvents = append(data.AuditEvents, events...)
+		if len(events) < exportPageSize || page*exportPageSize >= total {
+			break
+		}
+	}
+	return data, nil
+}
+
+// archive 生成zip：data.json为完整数据，summary.txt为便于阅读的摘要
+func (s *ExportService) archive(data *model.UserDataExport) ([]byte, error) {
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"data.json", content},
+		{"summary.txt", []byte(exportSummary(data))},
+	}
+	for _, file := range files {
+		w, err := writer.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: data.ExportedAt.Time,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(file.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// exportSummary 生成导出包的摘要
+func exportSummary(data *model.UserDataExport) string {
+	var b strings.Builder
+	user := data.User
+	fmt.Fprintf(&b, "个人数据导出\n导出时间: %s\n\n", data.ExportedAt.String())
+
+	b.WriteString("== 账号 ==\n")
+	fmt.Fprintf(&b, "用户ID: %d\n用户名: %s\n邮箱: %s\n显示名称: %s\n手机号: %s\n状态: %s\n",
+		user.Id, user.Username, user.Email, user.DisplayName, user.Phone, model.UserStatusName(user.Status))
+	if user.CreatedAt != nil {
+		fmt.Fprintf(&b, "注册时间: %s\n", user.CreatedAt.String())
+	}
+
+	fmt.Fprintf(&b, "\n== 状态变更（%d条） ==\n", len(data.StatusHistory))
+	for _, change := range data.StatusHistory {
+		fmt.Fprintf(&b, "%s  %s -> %s  %s\n", change.CreatedAt.String(),
+			model.UserStatusName(change.FromStatus), model.UserStatusName(change.ToStatus), change.Reason)
+	}
+
+	fmt.Fprintf(&b, "\n== 登录会话（%d个） ==\n", len(data.Sessions))
+	for _, session := range data.Sessions {
+		fmt.Fprintf(&b, "%s  IP: %s  %s\n", session.CreatedAt.String(), session.Ip, session.UserAgent)
+	}
+
+	fmt.Fprintf(&b, "\n== 个人访问令牌（%d个） ==\n", len(data.PersonalAccessTokens))
+	for _, token := range data.PersonalAccessTokens {
+		fmt.Fprintf(&b, "%s  %s（%s...）  权限: %s\n", token.CreatedAt.String(), token.Name, token.Prefix, token.Scopes)
+	}
+
+	fmt.Fprintf(&b, "\n== 刷新令牌: %d个 ==\n", len(data.RefreshTokens))
+
+	fmt.Fprintf(&b, "\n== 审计事件（%d条） ==\n", len(data.AuditEvents))
+	for _, event := range data.AuditEvents {
+		fmt.Fprintf(&b, "%s  %s  IP: %s\n", event.CreatedAt.String(), event.EventType, event.Ip)
+	}
+
+	b.WriteString("\n完整数据见data.json。\n")
+	return b.String()
+}
+
+// recordExported 记录导出审计事件
+func (s *ExportService) recordExported(ctx context.Context, user *model.User, mode string, detail g.Map) {
+	if detail == nil {
+		detail = g.Map{}
+	}
+	detail["mode"] = mode
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventDataExported,
+		UserId:    user.Id,
+		Username:  user.Username,
+		Success:   true,
+		Detail:    detail,
+	})
+}
+
+// cleanupExpired 定期删除下载链接已过期和生成中断的导出
+func (s *ExportService) cleanupExpired() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(exportCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := dao.DataExport.DeleteExpired(ctx, gtime.Now().Add(-exportStaleAfter))
+		if err != nil {
+			g.Log().Warning(ctx, "Failed to cleanup data exports:", err)
+			continue
+		}
+		if deleted > 0 {
+			g.Log().Debug(ctx, "Cleaned up data exports:", deleted)
+		}
+	}
+}
+
+// hashDownloadToken 计算下载令牌的SHA-256摘要，数据库只保存摘要
+func hashDownloadToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
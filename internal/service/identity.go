@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// 调用方身份来源
+const (
+	IdentitySourceCasdoor       = "casdoor"               // Casdoor签发的访问令牌
+	IdentitySourceLocal         = "local"                 // 本服务签发的会话令牌
+	IdentitySourcePersonalToken = "personal_access_token" // 个人访问令牌
+	IdentitySourceService       = "service"               // 服务间调用（client_credentials）
+)
+
+// Identity 已认证的调用方身份：本地用户及其在Casdoor中的角色和权限
+// 服务间调用时User为nil，调用方由Service表示
+type Identity struct {
+	User        *model.User       `json:"user"`
+	Service     *ServicePrincipal `json:"service"`
+	Owner       string            `json:"owner"`       // 用户所属的Casdoor组织
+	Roles       []string          `json:"roles"`       // 角色名称（不含组织前缀）
+	Permissions []string          `json:"permissions"` // 权限名称（不含组织前缀）
+	IsAdmin     bool              `json:"isAdmin"`     // Casdoor组织管理员
+	Source      string            `json:"source"`      // 身份来源
+	Scopes      []string          `json:"scopes"`      // 令牌的权限范围，nil表示交互式会话不受限
+	TokenId     uint64            `json:"tokenId"`     // 个人访问令牌ID
+	SessionId   uint64            `json:"sessionId"`   // 登录会话ID（没有会话记录时为0）
+}
+
+// newIdentity 根据Casdoor用户信息构建调用方身份
+func newIdentity(user *model.User, casdoorUser *casdoorsdk.User) *Identity {
+	identity := &Identity{
+		User:    user,
+		Owner:   casdoorUser.Owner,
+		IsAdmin: casdoorUser.IsAdmin,
+		Source:  IdentitySourceCasdoor,
+	}
+	for _, role := range casdoorUser.Roles {
+		if role != nil && role.Name != "" {
+			identity.Roles = append(identity.Roles, role.Name)
+		}
+	}
+	for _, permission := range casdoorUser.Permissions {
+		if permission != nil && permission.Name != "" {
+			identity.Permissions = append(identity.Permissions, permission.Name)
+		}
+	}
+	return identity
+}
+
+// HasRole 判断是否拥有指定角色，角色可写作"name"或"owner/name"
+func (i *Identity) HasRole(role string) bool {
+	return containsName(i.Roles, i.Owner, role)
+}
+
+// HasPermission 判断是否拥有指定权限，权限可写作"name"或"owner/name"
+func (i *Identity) HasPermission(permission string) bool {
+	return containsName(i.Permissions, i.Owner, permission)
+}
+
+// IsAdministrator 判断是否为管理员：Casdoor组织管理员或拥有auth.adminRoles中的任意角色
+func (i *Identity) IsAdministrator() bool {
+	if i.IsAdmin {
+		return true
+	}
+	for _, role := range Casdoor.appConfig.AdminRoles {
+		if i.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPersonalToken 判断是否通过个人访问令牌认证
+func (i *Identity) IsPersonalToken() bool {
+	return i.Source == IdentitySourcePersonalToken
+}
+
+// IsService 判断是否为服务调用方
+func (i *Identity) IsService() bool {
+	return i.Service != nil
+}
+
+// Name 返回调用方名称，用于日志
+func (i *Identity) Name() string {
+	if i.Service != nil {
+		return "service:" + i.Service.Name
+	}
+	return i.User.Username
+}
+
+// HasScope 判断令牌是否具有指定权限范围，交互式会话（Scopes为nil）不受限
+func (i *Identity) HasScope(scope string) bool {
+	if i.Scopes == nil {
+		return true
+	}
+	return containsString(i.Scopes, scope)
+}
+
+// Subject 返回Casbin请求中的主体（owner/name）
+func (i *Identity) Subject() string {
+	if i.Service != nil {
+		return i.Owner + "/" + i.Service.ClientId
+	}
+	return i.Owner + "/" + i.User.Username
+}
+
+// containsName 在名称列表中查找，带组织前缀时要求组织一致
+func containsName(names []string, owner, name string) bool {
+	if prefix, rest, found := strings.Cut(name, "/"); found {
+		if prefix != owner {
+			return false
+		}
+		name = rest
+	}
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate 验证token并返回调用方身份（支持个人访问令牌、本地签发的会话token、Casdoor用户token和应用token）
+// 角色和权限取自Casdoor token的声明，个人访问令牌和本地会话token不携带角色信息
+func (s *CasdoorService) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if PersonalToken.IsPersonalToken(token) {
+		return PersonalToken.Authenticate(ctx, token)
+	}
+
+	if Token.IsLocalToken(token) {
+		user, session, err := s.verifyLocalToken(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{User: user, Owner: s.config.OrganizationName, Source: IdentitySourceLocal, SessionId: session.Id}, nil
+	}
+
+	// 直接解析Casdoor JWT token
+	claims, err := s.parseSignedToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	// client_credentials签发的应用令牌代表服务而非用户
+	if ServiceClient.isApplicationToken(claims) {
+		return ServiceClient.authenticate(ctx, claims)
+	}
+
+	if err := s.validateClaims(claims); err != nil {
+		g.Log().Warning(ctx, "Token claims rejected:", err, "user:", claims.User.Name)
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if err := s.checkRevoked(ctx, &claims.RegisteredClaims, token, claims.User.Name); err != nil {
+		return nil, err
+	}
+
+	// 已结束的会话不能继续使用，同时更新会话的最近活跃时间
+	session, err := Session.Check(ctx, &claims.RegisteredClaims, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.localUser(ctx, &claims.User)
+	if err != nil {
+		return nil, err
+	}
+	identity := newIdentity(user, &claims.User)
+	if session != nil {
+		identity.SessionId = session.Id
+	}
+	return identity, nil
+}
+
+// Enforce 通过Casdoor权限模型检查调用方能否对资源执行操作
+// permissionId格式为"owner/name"，请求为 [subject, object, action]
+func (s *CasdoorService) Enforce(ctx context.Context, identity *Identity, permissionId, object, action string) (bool, error) {
+	allowed, err := casdoorsdk.Enforce(permissionId, "", "", casdoorsdk.CasbinRequest{identity.Subject(), object, action})
+	if err != nil {
+		g.Log().Error(ctx, "Casdoor enforce failed:", err)
+		return false, err
+	}
+	return allowed, nil
+}
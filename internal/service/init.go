@@ -10,10 +10,70 @@ import (
 func Init(ctx context.Context) {
 	g.Log().Info(ctx, "Initializing services...")
 
+	// 执行数据库迁移（按配置开启，需在其他服务访问数据库之前完成）
+	if err := Migrate.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to apply database migrations:", err)
+	}
+
 	// 初始化Casdoor服务
 	if err := Casdoor.Init(ctx); err != nil {
 		g.Log().Fatal(ctx, "Failed to initialize Casdoor service:", err)
 	}
 
+	// 初始化本地Token服务（依赖Casdoor服务的配置）
+	if err := Token.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize token service:", err)
+	}
+
+	// 初始化令牌吊销服务
+	if err := Revocation.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize revocation service:", err)
+	}
+
+	// 初始化个人访问令牌服务
+	if err := PersonalToken.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize personal access token service:", err)
+	}
+
+	// 初始化服务客户端（client_credentials）
+	if err := ServiceClient.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize service clients:", err)
+	}
+
+	// 初始化认证接口限流
+	if err := RateLimit.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize rate limiting:", err)
+	}
+
+	// 初始化Cookie会话模式（BFF）
+	if err := BFF.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize cookie session mode:", err)
+	}
+
+	// 初始化会话服务
+	if err := Session.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize session service:", err)
+	}
+
+	// 初始化Casdoor Webhook接收
+	if err := Webhook.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize webhook receiver:", err)
+	}
+
+	// 初始化用户对账服务
+	if err := Reconcile.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize reconciliation service:", err)
+	}
+
+	// 初始化账号删除服务
+	if err := Account.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize account service:", err)
+	}
+
+	// 初始化个人数据导出服务
+	if err := Export.Init(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to initialize data export service:", err)
+	}
+
 	g.Log().Info(ctx, "All services initialized successfully")
 }
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// jwksMinRefreshInterval 两次JWKS刷新之间的最小间隔（防止未知kid触发频繁请求）
+	jwksMinRefreshInterval = 30 * time.Second
+	// jwksMaxBackoff 刷新失败后的最大退避时间
+	jwksMaxBackoff = 10 * time.Minute
+	// jwksRefreshInterval 后台定期刷新间隔
+	jwksRefreshInterval = time.Hour
+)
+
+// jsonWebKey JWKS中的单个公钥
+type jsonWebKey struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use"`
+	Alg string   `json:"alg"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	X5c []string `json:"x5c"`
+}
+
+// KeyProvider Casdoor签名公钥提供者
+// 按kid缓存JWKS中的公钥，遇到未知kid时刷新（带退避），JWKS不可用时回退到配置的PEM公钥
+type KeyProvider struct {
+	jwksURL  string
+	fallback *rsa.PublicKey
+
+	mutex       sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	failures    int
+	nextRefresh time.Time // 在此之前不再发起刷新
+}
+
+// newKeyProvider 创建公钥提供者并尝试首次加载JWKS
+func newKeyProvider(ctx context.Context, jwksURL, fallbackPEM string) *KeyProvider {
+	p := &KeyProvider{
+		jwksURL: jwksURL,
+		keys:    make(map[string]*rsa.PublicKey),
+	}
+
+	if fallbackPEM != "" {
+		if key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(fallbackPEM)); err == nil {
+			p.fallback = key
+		} else {
+			g.Log().Warning(ctx, "⚠️ 配置的JWT公钥无法解析，将仅使用JWKS:", err)
+		}
+	}
+
+	if err := p.refresh(ctx); err != nil {
+		g.Log().Warning(ctx, "⚠️ 首次加载JWKS失败，暂时使用配置的公钥:", err)
+	}
+
+	go p.refreshPeriodically()
+	return p
+}
+
+// Keyfunc 返回jwt解析使用的Keyfunc
+func (p *KeyProvider) Keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if key := p.lookup(kid); key != nil {
+			return key, nil
+		}
+
+		// 未知kid：可能是Casdoor轮换了证书，刷新JWKS后重试
+		if kid != "" && p.refreshAllowed() {
+			if err := p.refresh(ctx); err != nil {
+				g.Log().Warning(ctx, "Failed to refresh JWKS:", err)
+			} else if key := p.lookup(kid); key != nil {
+				return key, nil
+			}
+		}
+
+		if p.fallback != nil {
+			return p.fallback, nil
+		}
+		return nil, fmt.Errorf("no signing key found for kid: %s", kid)
+	}
+}
+
+// lookup 按kid查找缓存的公钥；没有kid且JWKS只有一个公钥时直接使用该公钥
+func (p *KeyProvider) lookup(kid string) *rsa.PublicKey {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if kid != "" {
+		return p.keys[kid]
+	}
+	if len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// refreshAllowed 判断当前是否允许发起刷新（最小间隔和失败退避）
+func (p *KeyProvider) refreshAllowed() bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return time.Now().After(p.nextRefresh)
+}
+
+// refresh 拉取JWKS并替换缓存
+func (p *KeyProvider) refresh(ctx context.Context) error {
+	keys, err := p.fetch(ctx)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if err != nil {
+		p.failures++
+		backoff := jwksMinRefreshInterval << uint(min(p.failures, 5))
+		if backoff > jwksMaxBackoff {
+			backoff = jwksMaxBackoff
+		}
+		p.nextRefresh = time.Now().Add(backoff)
+		return err
+	}
+
+	p.keys = keys
+	p.failures = 0
+	p.nextRefresh = time.Now().Add(jwksMinRefreshInterval)
+	g.Log().Debug(ctx, "JWKS refreshed, keys:", len(keys))
+	return nil
+}
+
+// fetch 请求JWKS接口并解析其中的RSA公钥
+func (p *KeyProvider) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	response, err := g.Client().Timeout(10*time.Second).Get(ctx, p.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Close()
+
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("jwks endpoint returned status %d", response.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(response.ReadAll(), &jwks); err != nil {
+		return nil, fmt.Errorf("invalid jwks response: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.rsaPublicKey()
+		if err != nil {
+			g.Log().Warning(ctx, "Skipping invalid JWK:", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks contains no usable RSA keys")
+	}
+	return keys, nil
+}
+
+// refreshPeriodically 定期刷新JWKS，提前获取轮换后的公钥
+func (p *KeyProvider) refreshPeriodically() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(jwksRefreshInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := p.refresh(ctx); err != nil {
+			g.Log().Warning(ctx, "Periodic JWKS refresh failed:", err)
+		}
+	}
+}
+
+// rsaPublicKey 将JWK转换为RSA公钥（优先使用n/e，其次使用x5c证书）
+func (k *jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	if k.N != "" && k.E != "" {
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+
+	if len(k.X5c) > 0 {
+		pem := "-----BEGIN CERTIFICATE-----\n" + k.X5c[0] + "\n-----END CERTIFICATE-----"
+		return jwt.ParseRSAPublicKeyFromPEM([]byte(pem))
+	}
+
+	return nil, fmt.Errorf("jwk has neither n/e nor x5c")
+}
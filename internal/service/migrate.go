@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"context-id-backend/sql/migrations"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// migrationFilePattern 迁移文件名：<版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrNoMigrationToRevert 没有可回滚的迁移
+var ErrNoMigrationToRevert = errors.New("no applied migration to revert")
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的应用状态
+type MigrationStatus struct {
+	Version   int64       `json:"version"`
+	Name      string      `json:"name"`
+	Applied   bool        `json:"applied"`
+	AppliedAt *gtime.Time `json:"appliedAt,omitempty"`
+	Missing   bool        `json:"missing,omitempty"` // 数据库中已应用，但当前二进制中没有该迁移
+}
+
+// MigrateService 数据库迁移：版本化的up/down脚本编译进二进制，已应用的版本记录在schema_migrations表
+type MigrateService struct{}
+
+var Migrate = &MigrateService{}
+
+// Init 启动时按配置自动执行未应用的迁移（默认关闭，也可通过 migrate up 子命令手动执行）
+func (s *MigrateService) Init(ctx context.Context) error {
+	auto := false
+	if value, err := g.Cfg().Get(ctx, "migrate.auto"); err == nil && !value.IsEmpty() {
+		auto = value.Bool()
+	}
+
+	// 环境变量覆盖配置文件
+	if value := os.Getenv("DB_AUTO_MIGRATE"); value != "" {
+		auto = value == "true"
+	}
+
+	if !auto {
+		g.Log().Info(ctx, "启动时自动迁移未启用")
+		return nil
+	}
+
+	applied, err := s.Up(ctx)
+	if err != nil {
+		return err
+	}
+	g.Log().Info(ctx, "✅ 数据库迁移完成，本次应用:", len(applied))
+	return nil
+}
+
+// Status 列出全部迁移及其应用状态
+func (s *MigrateService) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	all, applied, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[int64]*MigrationStatus, len(all))
+	for _, migration := range all {
+		statuses[migration.Version] = &MigrationStatus{Version: migration.Version, Name: migration.Name}
+	}
+	for _, record := range applied {
+		status, exists := statuses[record.Version]
+		if !exists {
+			status = &MigrationStatus{Version: record.Version, Name: record.Name, Missing: true}
+			statuses[record.Version] = status
+		}
+		status.Applied = true
+		status.AppliedAt = record.AppliedAt
+	}
+
+	result := make([]*MigrationStatus, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Up 按版本号顺序应用所有未应用的迁移，返回本次应用的迁移
+func (s *MigrateService) Up(ctx context.Context) ([]*Migration, error) {
+	all, records, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(records))
+	for _, record := range records {
+		done[record.Version] = true
+	}
+
+	var applied []*Migration
+	for _, migration := range all {
+		if done[migration.Version] {
+			continue
+		}
+		ok, err := dao.SchemaMigration.Apply(ctx, migration.Version, migration.Name, migration.Up)
+		if err != nil {
+			return applied, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if ok {
+			g.Log().Infof(ctx, "Applied migration %04d_%s", migration.Version, migration.Name)
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// Down 回滚最近应用的一个迁移
+func (s *MigrateService) Down(ctx context.Context) (*Migration, error) {
+	migration, err := s.latestApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if migration.Down == "" {
+		return nil, fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+	}
+
+	if _, err := dao.SchemaMigration.Revert(ctx, migration.Version, migration.Down); err != nil {
+		return nil, fmt.Errorf("revert %04d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	g.Log().Infof(ctx, "Reverted migration %04d_%s", migration.Version, migration.Name)
+	return migration, nil
+}
+
+// Redo 回滚并重新应用最近应用的一个迁移
+func (s *MigrateService) Redo(ctx context.Context) (*Migration, error) {
+	migration, err := s.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dao.SchemaMigration.Apply(ctx, migration.Version, migration.Name, migration.Up); err != nil {
+		return nil, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	g.Log().Infof(ctx, "Applied migration %04d_%s", migration.Version, migration.Name)
+	return migration, nil
+}
+
+// load 读取全部迁移脚本和已应用的迁移记录
+func (s *MigrateService) load(ctx context.Context) ([]*Migration, []*model.SchemaMigration, error) {
+	all, err := s.migrations()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := dao.SchemaMigration.EnsureTable(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	applied, err := dao.SchemaMigration.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return all, applied, nil
+}
+
+// latestApplied 获取最近应用（版本号最大）的迁移
+func (s *MigrateService) latestApplied(ctx context.Context) (*Migration, error) {
+	all, applied, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		return nil, ErrNoMigrationToRevert
+	}
+
+	latest := applied[len(applied)-1]
+	for _, migration := range all {
+		if migration.Version == latest.Version {
+			return migration, nil
+		}
+	}
+	return nil, fmt.Errorf("applied migration %04d_%s is not in this build", latest.Version, latest.Name)
+}
+
+// migrations 解析编译进二进制的迁移脚本（按版本号升序）
+func (s *MigrateService) migrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(migrations.FS, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names: %s, %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", migration.Version, migration.Name)
+		}
+		result = append(result, migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// PersonalTokenPrefix 个人访问令牌前缀，用于与JWT区分并便于密钥扫描工具识别
+const PersonalTokenPrefix = "cid_pat_"
+
+// personalTokenTouchInterval 同一IP重复使用令牌时，最近使用时间的最小更新间隔
+const personalTokenTouchInterval = time.Minute
+
+var (
+	// ErrPersonalTokenNotFound 个人访问令牌不存在或已被吊销
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+	// ErrPersonalTokenScope 请求的权限范围不在允许列表中
+	ErrPersonalTokenScope = errors.New("invalid personal access token scope")
+	// ErrPersonalTokenTTL 请求的有效期超过上限
+	ErrPersonalTokenTTL = errors.New("personal access token expiry exceeds the limit")
+	// ErrPersonalTokenLogout 个人访问令牌不能用于登出
+	ErrPersonalTokenLogout = errors.New("personal access tokens cannot be logged out, revoke them instead")
+)
+
+// PersonalTokenConfig 个人访问令牌配置结构体
+type PersonalTokenConfig struct {
+	DefaultTTL    time.Duration // 未指定有效期时使用的默认有效期
+	MaxTTL        time.Duration // 允许的最长有效期
+	Scopes        []string      // 允许申请的权限范围
+	DefaultScopes []string      // 未指定权限范围时使用的默认范围
+}
+
+// PersonalTokenService 个人访问令牌服务
+type PersonalTokenService struct {
+	config *PersonalTokenConfig
+}
+
+var PersonalToken = &PersonalTokenService{}
+
+// Init 加载个人访问令牌配置
+func (s *PersonalTokenService) Init(ctx context.Context) error {
+	config := &PersonalTokenConfig{
+		DefaultTTL:    90 * 24 * time.Hour,
+		MaxTTL:        365 * 24 * time.Hour,
+		Scopes:        []string{"read", "write"},
+		DefaultScopes: []string{"read"},
+	}
+	cfg := g.Cfg()
+
+	if defaultTTL, err := cfg.Get(ctx, "personalAccessToken.defaultTtl"); err == nil && !defaultTTL.IsEmpty() {
+		config.DefaultTTL = defaultTTL.Duration()
+	}
+	if maxTTL, err := cfg.Get(ctx, "personalAccessToken.maxTtl"); err == nil && !maxTTL.IsEmpty() {
+		config.MaxTTL = maxTTL.Duration()
+	}
+	if scopes, err := cfg.Get(ctx, "personalAccessToken.scopes"); err == nil && !scopes.IsEmpty() {
+		config.Scopes = scopes.Strings()
+	}
+	if defaultScopes, err := cfg.Get(ctx, "personalAccessToken.defaultScopes"); err == nil && !defaultScopes.IsEmpty() {
+		config.DefaultScopes = defaultScopes.Strings()
+	}
+
+	if config.DefaultTTL > config.MaxTTL {
+		return fmt.Errorf("personalAccessToken.defaultTtl 不能大于 maxTtl")
+	}
+
+	s.config = config
+	g.Log().Info(ctx, "✅ 个人访问令牌配置加载完成:")
+	g.Log().Info(ctx, "   - Default TTL:", config.DefaultTTL)
+	g.Log().Info(ctx, "   - Max TTL:", config.MaxTTL)
+	g.Log().Info(ctx, "   - Scopes:", config.Scopes)
+	return nil
+}
+
+// IsPersonalToken 判断令牌是否为个人访问令牌
+func (s *PersonalTokenService) IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// Create 为用户创建个人访问令牌，返回的明文令牌只在创建时出现一次
+func (s *PersonalTokenService) Create(ctx context.Context, user *model.User, req *model.PersonalAccessTokenCreateReq) (string, *model.PersonalAccessToken, error) {
+	scopes, err := s.resolveScopes(req.Scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ttl := s.config.DefaultTTL
+	if req.ExpiresInDays > 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	if ttl > s.config.MaxTTL {
+		return "", nil, ErrPersonalTokenTTL
+	}
+
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", nil, fmt.Errorf("failed to generate personal access token: %w", err)
+	}
+	secret := PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+
+	record := &model.PersonalAccessToken{
+		UserId:    user.Id,
+		Name:      req.Name,
+		TokenHash: hashRefreshToken(secret),
+		Prefix:    secret[:len(PersonalTokenPrefix)+4],
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: gtime.Now().Add(ttl),
+		CreatedAt: gtime.Now(),
+	}
+	if err := dao.PersonalAccessToken.Create(ctx, record); err != nil {
+		g.Log().Error(ctx, "Failed to create personal access token:", err)
+		return "", nil, err
+	}
+
+	g.Log().Info(ctx, "Personal access token created:", record.Id, "user:", user.Username, "scopes:", record.Scopes)
+	return secret, record, nil
+}
+
+// resolveScopes 校验申请的权限范围，未指定时使用默认范围
+func (s *PersonalTokenService) resolveScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return s.config.DefaultScopes, nil
+	}
+
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !containsString(s.config.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrPersonalTokenScope, scope)
+		}
+		if !containsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// List 获取用户的个人访问令牌
+func (s *PersonalTokenService) List(ctx context.Context, userId uint64) ([]*model.PersonalAccessToken, error) {
+	return dao.PersonalAccessToken.ListByUser(ctx, userId)
+}
+
+// Revoke 吊销用户的个人访问令牌
+func (s *PersonalTokenService) Revoke(ctx context.Context, userId, id uint64) error {
+	revoked, err := dao.PersonalAccessToken.Revoke(ctx, userId, id)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to revoke personal access token:", err)
+		return err
+	}
+	if !revoked {
+		return ErrPersonalTokenNotFound
+	}
+
+	g.Log().Info(ctx, "Personal access token revoked:", id, "user id:", userId)
+	return nil
+}
+
+// Authenticate 验证个人访问令牌，并记录最近使用的时间和IP
+func (s *PersonalTokenService) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	record, err := dao.PersonalAccessToken.GetByHash(ctx, hashRefreshToken(token))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if record == nil {
+		return nil, &TokenError{Code: TokenErrInvalid, Message: "个人访问令牌无效"}
+	}
+	if record.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+	if record.ExpiresAt != nil && record.ExpiresAt.Before(gtime.Now()) {
+		return nil, &TokenError{Code: TokenErrExpired, Message: "个人访问令牌已过期"}
+	}
+
+	user, err := Casdoor.users.GetById(ctx, record.UserId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if user == nil {
+		return nil, &TokenError{Code: TokenErrInvalid, Message: "个人访问令牌无效"}
+	}
+
+	s.touch(ctx, record)
+
+	return &Identity{
+		User:    user,
+		Owner:   Casdoor.config.OrganizationName,
+		Source:  IdentitySourcePersonalToken,
+		Scopes:  strings.Fields(record.Scopes),
+		TokenId: record.Id,
+	}, nil
+}
+
+// touch 更新最近使用的时间和IP（同一IP短时间内重复使用时跳过，减少写库）
+func (s *PersonalTokenService) touch(ctx context.Context, record *model.PersonalAccessToken) {
+	ip := ""
+	if r := g.RequestFromCtx(ctx); r != nil {
+		ip = r.GetClientIp()
+	}
+	if record.LastUsedAt != nil && record.LastUsedIp == ip &&
+		gtime.Now().Sub(record.LastUsedAt) < personalTokenTouchInterval {
+		return
+	}
+
+	if err := dao.PersonalAccessToken.TouchLastUsed(ctx, record.Id, ip); err != nil {
+		g.Log().Warning(ctx, "Failed to record personal access token usage:", err)
+	}
+}
+
+// containsString 判断字符串切片中是否包含指定值
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+)
+
+// 支持的限流后端
+const (
+	RateLimitMemory = "memory"
+	RateLimitRedis  = "redis"
+)
+
+// RateLimitRule 令牌桶规则：桶容量为Limit，每个Window内补满
+type RateLimitRule struct {
+	Limit  int           `json:"limit"`
+	Window time.Duration `json:"window"`
+}
+
+// ratePerSecond 每秒补充的令牌数
+func (r *RateLimitRule) ratePerSecond() float64 {
+	return float64(r.Limit) / r.Window.Seconds()
+}
+
+// RateLimitConfig 限流配置结构体
+type RateLimitConfig struct {
+	Enabled           bool
+	Backend           string         // memory（单实例）/ redis（多副本共享）
+	TrustForwardedFor bool           // 是否信任X-Forwarded-For识别客户端IP（仅在可信反向代理之后开启）
+	IP                *RateLimitRule // 按客户端IP限流
+	User              *RateLimitRule // 按已认证的调用方限流
+}
+
+// RateLimitResult 一次限流判断的结果，用于生成RateLimit-*响应头
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 令牌桶补满所需时间
+	RetryAfter time.Duration // 被拒绝时，距下一个可用令牌的时间
+	Policy     string        // RateLimit-Policy响应头（如 "60;w=60"）
+}
+
+// rateLimiter 令牌桶限流后端
+type rateLimiter interface {
+	// Take 从key对应的令牌桶中取出一个令牌
+	Take(ctx context.Context, key string, rule *RateLimitRule) (*RateLimitResult, error)
+}
+
+// RateLimitService 认证接口的限流服务（按IP和按调用方的令牌桶）
+type RateLimitService struct {
+	config  *RateLimitConfig
+	limiter rateLimiter
+}
+
+var RateLimit = &RateLimitService{}
+
+// Init 加载限流配置并创建限流后端
+func (s *RateLimitService) Init(ctx context.Context) error {
+	config := &RateLimitConfig{
+		Enabled: true,
+		Backend: RateLimitMemory,
+		IP:      &RateLimitRule{Limit: 60, Window: time.Minute},
+		User:    &RateLimitRule{Limit: 120, Window: time.Minute},
+	}
+	cfg := g.Cfg()
+
+	if enabled, err := cfg.Get(ctx, "rateLimit.enabled"); err == nil && !enabled.IsEmpty() {
+		config.Enabled = enabled.Bool()
+	}
+	if backend, err := cfg.Get(ctx, "rateLimit.backend"); err == nil && !backend.IsEmpty() {
+		config.Backend = backend.String()
+	}
+	if trust, err := cfg.Get(ctx, "rateLimit.trustForwardedFor"); err == nil && !trust.IsEmpty() {
+		config.TrustForwardedFor = trust.Bool()
+	}
+	if ip, err := cfg.Get(ctx, "rateLimit.ip"); err == nil && !ip.IsEmpty() {
+		if err := ip.Scan(config.IP); err != nil {
+			return fmt.Errorf("rateLimit.ip 配置格式错误: %w", err)
+		}
+	}
+	if user, err := cfg.Get(ctx, "rateLimit.user"); err == nil && !user.IsEmpty() {
+		if err := user.Scan(config.User); err != nil {
+			return fmt.Errorf("rateLimit.user 配置格式错误: %w", err)
+		}
+	}
+
+	// 环境变量覆盖配置文件
+	if enabled := os.Getenv("RATE_LIMIT_ENABLED"); enabled != "" {
+		config.Enabled = enabled == "true"
+	}
+	if backend := os.Getenv("RATE_LIMIT_BACKEND"); backend != "" {
+		config.Backend = backend
+	}
+
+	s.config = config
+	if !config.Enabled {
+		g.Log().Info(ctx, "限流未启用")
+		return nil
+	}
+
+	for name, rule := range map[string]*RateLimitRule{"ip": config.IP, "user": config.User} {
+		if rule.Limit <= 0 || rule.Window <= 0 {
+			return fmt.Errorf("rateLimit.%s 的limit和window必须大于0", name)
+		}
+	}
+
+	switch config.Backend {
+	case RateLimitMemory:
+		limiter := newMemoryRateLimiter()
+		go limiter.cleanup()
+		s.limiter = limiter
+	case RateLimitRedis:
+		s.limiter = newRedisRateLimiter()
+	default:
+		return fmt.Errorf("unsupported rate limit backend: %s", config.Backend)
+	}
+
+	g.Log().Info(ctx, "✅ 限流配置加载完成:")
+	g.Log().Info(ctx, "   - Backend:", config.Backend)
+	g.Log().Info(ctx, "   - Per IP:", config.IP.Limit, "/", config.IP.Window)
+	g.Log().Info(ctx, "   - Per User:", config.User.Limit, "/", config.User.Window)
+	return nil
+}
+
+// Enabled 是否启用限流
+func (s *RateLimitService) Enabled() bool {
+	return s.config != nil && s.config.Enabled
+}
+
+// TrustForwardedFor 是否按X-Forwarded-For识别客户端IP
+func (s *RateLimitService) TrustForwardedFor() bool {
+	return s.config != nil && s.config.TrustForwardedFor
+}
+
+// TakeIP 按客户端IP取令牌
+func (s *RateLimitService) TakeIP(ctx context.Context, ip string) (*RateLimitResult, error) {
+	return s.limiter.Take(ctx, "ip:"+ip, s.config.IP)
+}
+
+// TakeCaller 按已认证的调用方取令牌
+func (s *RateLimitService) TakeCaller(ctx context.Context, identity *Identity) (*RateLimitResult, error) {
+	// 服务调用方的名称自带"service:"前缀
+	key := identity.Name()
+	if !identity.IsService() {
+		key = "user:" + key
+	}
+	return s.limiter.Take(ctx, key, s.config.User)
+}
+
+// newRateLimitResult 根据取令牌后桶内剩余的令牌数计算结果
+func newRateLimitResult(rule *RateLimitRule, allowed bool, tokens float64) *RateLimitResult {
+	rate := rule.ratePerSecond()
+	result := &RateLimitResult{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(rule.Limit) - tokens) / rate * float64(time.Second)),
+		Policy:    fmt.Sprintf("%d;w=%d", rule.Limit, int(rule.Window.Seconds())),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+// memoryBucket 进程内令牌桶
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rule      *RateLimitRule
+}
+
+// memoryRateLimiter 进程内令牌桶限流（仅适用于单实例部署）
+type memoryRateLimiter struct {
+	buckets map[string]*memoryBucket
+	mutex   sync.Mutex
+}
+
+func newMemoryRateLimiter() *memoryRateLimiter {
+	return &memoryRateLimiter{
+		buckets: make(map[string]*memoryBucket),
+	}
+}
+
+func (m *memoryRateLimiter) Take(ctx context.Context, key string, rule *RateLimitRule) (*RateLimitResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	bucket, exists := m.buckets[key]
+	if !exists {
+		bucket = &memoryBucket{tokens: float64(rule.Limit), updatedAt: now, rule: rule}
+		m.buckets[key] = bucket
+	}
+
+	// 按经过的时间补充令牌，不超过桶容量
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens = math.Min(float64(rule.Limit), bucket.tokens+elapsed*rule.ratePerSecond())
+	bucket.updatedAt = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return newRateLimitResult(rule, allowed, bucket.tokens), nil
+}
+
+// cleanup 定期删除已补满的令牌桶（补满的桶与新建的桶等价）
+func (m *memoryRateLimiter) cleanup() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(time.Minute) // 每分钟清理一次
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.mutex.Lock()
+		now := time.Now()
+		removed := 0
+		for key, bucket := range m.buckets {
+			refilled := bucket.tokens + now.Sub(bucket.updatedAt).Seconds()*bucket.rule.ratePerSecond()
+			if refilled >= float64(bucket.rule.Limit) {
+				delete(m.buckets, key)
+				removed++
+			}
+		}
+		m.mutex.Unlock()
+
+		if removed > 0 {
+			g.Log().Debug(ctx, "Cleaned up idle rate limit buckets:", removed)
+		}
+	}
+}
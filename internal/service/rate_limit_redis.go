@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// redisRateLimitKeyPrefix Redis中令牌桶键的前缀
+const redisRateLimitKeyPrefix = "rate_limit:"
+
+// redisTokenBucketScript 原子地补充并取出令牌，返回 {是否允许, 剩余令牌数}
+// 桶在补满所需的时间后自动过期
+const redisTokenBucketScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// redisRateLimiter 基于Redis的令牌桶限流，多副本共享限流状态
+type redisRateLimiter struct{}
+
+func newRedisRateLimiter() *redisRateLimiter {
+	return &redisRateLimiter{}
+}
+
+func (r *redisRateLimiter) Take(ctx context.Context, key string, rule *RateLimitRule) (*RateLimitResult, error) {
+	// 速率以毫秒为单位传入脚本
+	ratePerMs := rule.ratePerSecond() / 1000
+	reply, err := dao.Redis.Eval(ctx, redisTokenBucketScript, []string{redisRateLimitKeyPrefix + key},
+		rule.Limit, strconv.FormatFloat(ratePerMs, 'f', -1, 64), time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	allowed, _ := values[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(values[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected rate limit reply: %v", reply)
+	}
+	return newRateLimitResult(rule, allowed == 1, tokens), nil
+}
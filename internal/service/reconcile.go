@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcron"
+)
+
+// reconcileJobName 定时对账任务名称
+const reconcileJobName = "reconcile-users"
+
+// ErrReconcileRunning 已有对账任务在执行
+var ErrReconcileRunning = errors.New("reconciliation is already running")
+
+// ReconcileConfig 用户对账配置结构体
+type ReconcileConfig struct {
+	Enabled  bool   // 是否启用定时对账
+	Schedule string // 定时对账的cron表达式（含秒）
+	PageSize int    // 每次从Casdoor拉取的用户数
+}
+
+// ReconcileConflict 无法自动处理的差异（如邮箱与其他本地用户冲突）
+type ReconcileConflict struct {
+	Username  string `json:"username"`
+	CasdoorId string `json:"casdoorId"`
+	Reason    string `json:"reason"`
+}
+
+// ReconcileReport 一次对账的结果
+type ReconcileReport struct {
+	DryRun      bool                 `json:"dryRun"`
+	StartedAt   time.Time            `json:"startedAt"`
+	FinishedAt  time.Time            `json:"finishedAt"`
+	Fetched     int                  `json:"fetched"`     // 从Casdoor拉取的用户数
+	Created     int                  `json:"created"`     // 本地新建的用户数
+	Updated     int                  `json:"updated"`     // 资料有变化的用户数
+	Deactivated int                  `json:"deactivated"` // Casdoor中已不存在而变为已删除的用户数
+	Unchanged   int                  `json:"unchanged"`
+	Failed      int                  `json:"failed"` // 写入失败的用户数
+	Conflicts   []*ReconcileConflict `json:"conflicts"`
+}
+
+// conflict 记录一条冲突
+func (r *ReconcileReport) conflict(casdoorUser *casdoorsdk.User, format string, args ...interface{}) {
+	r.Conflicts = append(r.Conflicts, &ReconcileConflict{
+		Username:  casdoorUser.Name,
+		CasdoorId: casdoorUser.Id,
+		Reason:    fmt.Sprintf(format, args...),
+	})
+}
+
+// ReconcileService Casdoor与本地用户表的全量对账：补齐Webhook遗漏的注册、资料变更和删除
+type ReconcileService struct {
+	config  *ReconcileConfig
+	running sync.Mutex
+}
+
+var Reconcile = &ReconcileService{}
+
+// Init 加载对账配置
+func (s *ReconcileService) Init(ctx context.Context) error {
+	config := &ReconcileConfig{
+		Schedule: "0 0 3 * * *",
+		PageSize: 100,
+	}
+	cfg := g.Cfg()
+
+	if enabled, err := cfg.Get(ctx, "reconcile.enabled"); err == nil && !enabled.IsEmpty() {
+		config.Enabled = enabled.Bool()
+	}
+	if schedule, err := cfg.Get(ctx, "reconcile.schedule"); err == nil && !schedule.IsEmpty() {
+		config.Schedule = schedule.String()
+	}
+	if pageSize, err := cfg.Get(ctx, "reconcile.pageSize"); err == nil && !pageSize.IsEmpty() {
+		config.PageSize = pageSize.Int()
+	}
+
+	// 环境变量覆盖配置文件
+	if enabled := os.Getenv("RECONCILE_ENABLED"); enabled != "" {
+		config.Enabled = enabled == "true"
+	}
+
+	if config.PageSize <= 0 {
+		return fmt.Errorf("reconcile.pageSize 必须大于0")
+	}
+
+	s.config = config
+	return nil
+}
+
+// Schedule 注册定时对账任务（仅服务进程调用，命令行对账不注册）
+// 多副本部署时只应在一个副本上启用
+func (s *ReconcileService) Schedule(ctx context.Context) error {
+	if !s.config.Enabled {
+		g.Log().Info(ctx, "定时用户对账未启用")
+		return nil
+	}
+
+	_, err := gcron.AddSingleton(ctx, s.config.Schedule, func(ctx context.Context) {
+		if _, err := s.Run(ctx, false); err != nil {
+			g.Log().Error(ctx, "Scheduled user reconciliation failed:", err)
+		}
+	}, reconcileJobName)
+	if err != nil {
+		return fmt.Errorf("reconcile.schedule 配置错误: %w", err)
+	}
+
+	g.Log().Info(ctx, "✅ 定时用户对账已启用:", s.config.Schedule)
+	return nil
+}
+
+// Run 执行一次全量对账；dryRun为true时只统计差异，不修改本地数据
+func (s *ReconcileService) Run(ctx context.Context, dryRun bool) (*ReconcileReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrReconcileRunning
+	}
+	defer s.running.Unlock()
+
+	report := &ReconcileReport{DryRun: dryRun, StartedAt: time.Now(), Conflicts: []*ReconcileConflict{}}
+
+	// 先拉取全部Casdoor用户，拉取不完整时不能据此停用本地用户
+	casdoorUsers, err := s.fetchCasdoorUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch casdoor users: %w", err)
+	}
+	report.Fetched = len(casdoorUsers)
+
+	localUsers, err := Casdoor.users.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local users: %w", err)
+	}
+
+	index := newLocalUserIndex(localUsers)
+	if len(casdoorUsers) == 0 && index.countActive() > 0 {
+		return nil, fmt.Errorf("casdoor returned no users, refusing to deactivate %d local users", index.countActive())
+	}
+
+	seen := make(map[uint64]bool, len(localUsers))
+	for _, casdoorUser := range casdoorUsers {
+		s.reconcileUser(ctx, casdoorUser, index, seen, report)
+	}
+
+	// 本地存在但Casdoor中已不存在的用户（只改了状态、尚未软删除的已删除用户一并软删除）
+	for _, user := range localUsers {
+		if seen[user.Id] {
+			continue
+		}
+		if !dryRun {
+			if err := Casdoor.deactivateUser(ctx, user, "not found in casdoor", model.UserStatusSourceReconcile); err != nil {
+				g.Log().Warning(ctx, "Failed to deactivate user during reconciliation:", user.Username, err)
+				report.Failed++
+				continue
+			}
+		}
+		report.Deactivated++
+	}
+
+	report.FinishedAt = time.Now()
+	g.Log().Infof(ctx, "User reconciliation finished (dryRun=%t): fetched=%d created=%d updated=%d deactivated=%d unchanged=%d failed=%d conflicts=%d",
+		dryRun, report.Fetched, report.Created, report.Updated, report.Deactivated, report.Unchanged, report.Failed, len(report.Conflicts))
+	return report, nil
+}
+
+// fetchCasdoorUsers 分页拉取配置的组织下的全部用户（已标记删除的用户除外）
+func (s *ReconcileService) fetchCasdoorUsers() ([]*casdoorsdk.User, error) {
+	var users []*casdoorsdk.User
+	for page := 1; ; page++ {
+		items, total, err := casdoorsdk.GetPaginationUsers(page, s.config.PageSize, map[string]string{})
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range items {
+			if !user.IsDeleted {
+				users = append(users, user)
+			}
+		}
+		if len(items) < s.config.PageSize || page*s.config.PageSize >= total {
+			return users, nil
+		}
+	}
+}
+
+// reconcileUser 对比单个Casdoor用户与本地用户，按需新建或更新
+func (s *ReconcileService) reconcileUser(ctx context.Context, casdoorUser *casdoorsdk.User, index *localUserIndex, seen map[uint64]bool, report *ReconcileReport) {
+	local := index.byCasdoorId[casdoorUser.Id]
+	if local == nil {
+		local = index.byUsername[casdoorUser.Name]
+		// 用户名相同但已关联其他Casdoor用户，不能合并
+		if local != nil && local.CasdoorId != "" && local.CasdoorId != casdoorUser.Id {
+			report.conflict(casdoorUser, "username is bound to another casdoor user (%s)", local.CasdoorId)
+			return
+		}
+	}
+	if local != nil {
+		seen[local.Id] = true
+	}
+
+	// 本地表的用户名和邮箱有唯一约束，被其他用户占用时无法写入
+	if other := index.byUsername[casdoorUser.Name]; other != nil && other != local {
+		report.conflict(casdoorUser, "username is used by local user %d", other.Id)
+		return
+	}
+	if other := index.byEmail[casdoorUser.Email]; other != nil && other != local {
+		report.conflict(casdoorUser, "email %q is used by local user %d", casdoorUser.Email, other.Id)
+		return
+	}
+
+	desired := &model.User{
+		CasdoorId:   casdoorUser.Id,
+		Username:    casdoorUser.Name,
+		Email:       casdoorUser.Email,
+		DisplayName: casdoorUser.DisplayName,
+		Avatar:      casdoorUser.Avatar,
+		Phone:       casdoorUser.Phone,
+		Status:      model.UserStatusActive,
+	}
+	// 待验证的用户已完成邮箱验证时也需要同步，由SyncUser变更为正常
+	activate := local != nil && local.Status == model.UserStatusPending && Casdoor.emailVerified(casdoorUser)
+	if local != nil && local.CasdoorId == casdoorUser.Id && len(profileChanges(local, desired)) == 0 && !activate {
+		report.Unchanged++
+		return
+	}
+
+	if !report.DryRun {
+		if _, err := Casdoor.SyncUser(ctx, casdoorUser); err != nil {
+			g.Log().Warning(ctx, "Failed to sync user during reconciliation:", casdoorUser.Name, err)
+			report.Failed++
+			report.conflict(casdoorUser, "sync failed: %v", err)
+			return
+		}
+	}
+
+	if local == nil {
+		report.Created++
+	} else {
+		report.Updated++
+		desired.Id = local.Id
+		desired.Status = local.Status
+	}
+	// 更新索引，后续用户的唯一性检查基于对账后的数据
+	index.replace(local, desired)
+}
+
+// localUserIndex 本地用户的内存索引
+type localUserIndex struct {
+	byCasdoorId map[string]*model.User
+	byUsername  map[string]*model.User
+	byEmail     map[string]*model.User
+}
+
+func newLocalUserIndex(users []*model.User) *localUserIndex {
+	index := &localUserIndex{
+		byCasdoorId: make(map[string]*model.User, len(users)),
+		byUsername:  make(map[string]*model.User, len(users)),
+		byEmail:     make(map[string]*model.User, len(users)),
+	}
+	for _, user := range users {
+		index.add(user)
+	}
+	return index
+}
+
+func (i *localUserIndex) add(user *model.User) {
+	if user.CasdoorId != "" {
+		i.byCasdoorId[user.CasdoorId] = user
+	}
+	i.byUsername[user.Username] = user
+	i.byEmail[user.Email] = user
+}
+
+// replace 用对账后的用户替换原用户（原用户为nil时为新增）
+func (i *localUserIndex) replace(old, user *model.User) {
+	if old != nil {
+		if i.byCasdoorId[old.CasdoorId] == old {
+			delete(i.byCasdoorId, old.CasdoorId)
+		}
+		if i.byUsername[old.Username] == old {
+			delete(i.byUsername, old.Username)
+		}
+		if i.byEmail[old.Email] == old {
+			delete(i.byEmail, old.Email)
+		}
+	}
+	i.add(user)
+}
+
+// countActive 统计未删除的本地用户数
+func (i *localUserIndex) countActive() int {
+	count := 0
+	for _, user := range i.byUsername {
+		if user.Status != model.UserStatusDeleted {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// redirect_uri校验失败的错误码
+const (
+	RedirectErrInvalid    = "invalid_redirect_uri"
+	RedirectErrNotAllowed = "redirect_uri_not_allowed"
+	RedirectErrMismatch   = "redirect_uri_mismatch"
+)
+
+// RedirectURIError redirect_uri校验失败
+type RedirectURIError struct {
+	Code        string
+	RedirectURI string
+	Reason      string
+}
+
+func (e *RedirectURIError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", e.Code, e.Reason, e.RedirectURI)
+}
+
+// checkRedirectURI 校验redirect_uri是否在白名单中，防止被用作开放重定向
+// 白名单条目支持两种形式：
+//   - 完整URL（含路径、不含*）：要求完全一致，如 https://app.example.com/callback
+//   - host模式：可带scheme前缀，host支持"*."前缀通配子域名，端口支持"*"，
+//     如 https://app.example.com、*.example.com、https://*.example.com、localhost:*
+func (s *CasdoorService) checkRedirectURI(ctx context.Context, redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		g.Log().Warning(ctx, "Rejected malformed redirect_uri:", redirectURI)
+		return &RedirectURIError{Code: RedirectErrInvalid, RedirectURI: redirectURI, Reason: "redirect_uri必须是http(s)绝对地址"}
+	}
+	if parsed.User != nil || parsed.Fragment != "" {
+		g.Log().Warning(ctx, "Rejected redirect_uri with userinfo or fragment:", redirectURI)
+		return &RedirectURIError{Code: RedirectErrInvalid, RedirectURI: redirectURI, Reason: "redirect_uri不能包含用户信息或片段"}
+	}
+
+	for _, pattern := range s.appConfig.RedirectAllowlist {
+		if matchRedirectPattern(pattern, redirectURI, parsed) {
+			return nil
+		}
+	}
+
+	g.Log().Warning(ctx, "Rejected redirect_uri not in allowlist:", redirectURI, "env:", s.appConfig.Environment)
+	return &RedirectURIError{Code: RedirectErrNotAllowed, RedirectURI: redirectURI, Reason: "redirect_uri不在允许列表中"}
+}
+
+// matchRedirectPattern 判断redirect_uri是否匹配白名单条目
+func matchRedirectPattern(pattern, redirectURI string, parsed *url.URL) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return false
+	}
+
+	// 带路径的完整URL精确匹配
+	if strings.Contains(pattern, "://") && !strings.Contains(pattern, "*") {
+		if patternURL, err := url.Parse(pattern); err == nil && strings.Trim(patternURL.Path, "/") != "" {
+			return pattern == redirectURI
+		}
+	}
+
+	// host模式：可选的scheme限制
+	hostPattern := pattern
+	if scheme, rest, found := strings.Cut(pattern, "://"); found {
+		if scheme != parsed.Scheme {
+			return false
+		}
+		hostPattern = rest
+	}
+	hostPattern = strings.TrimRight(hostPattern, "/")
+
+	host, port := parsed.Hostname(), parsed.Port()
+	patternHost, patternPort, hasPort := strings.Cut(hostPattern, ":")
+	if hasPort {
+		if patternPort != "*" && patternPort != port {
+			return false
+		}
+	} else if port != "" {
+		// 未指定端口的模式只匹配默认端口
+		return false
+	}
+
+	// 域名不区分大小写
+	if suffix, found := strings.CutPrefix(patternHost, "*."); found {
+		return strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(suffix))
+	}
+	return strings.EqualFold(patternHost, host)
+}
+
+// defaultRedirectAllowlist 未配置白名单时仅允许应用自身的外部地址
+func defaultRedirectAllowlist(externalUrl string) []string {
+	parsed, err := url.Parse(externalUrl)
+	if err != nil || parsed.Host == "" {
+		return nil
+	}
+	return []string{parsed.Scheme + "://" + parsed.Host}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestCheckRedirectURI redirect_uri白名单：子域名通配、相似域名、用户信息、scheme和端口限制
+func TestCheckRedirectURI(t *testing.T) {
+	ctx := context.Background()
+	s := &CasdoorService{appConfig: &AppConfig{RedirectAllowlist: []string{
+		"https://*.example.com",
+		"https://app.example.org/callback",
+		"localhost:*",
+		"http://127.0.0.1:8080",
+	}}}
+
+	cases := []struct {
+		name        string
+		redirectURI string
+		code        string // 空表示允许
+	}{
+		{"wildcard subdomain", "https://app.example.com/callback", ""},
+		{"nested subdomain", "https://a.b.example.com/callback", ""},
+		{"uppercase host", "https://APP.EXAMPLE.COM/callback", ""},
+		{"wildcard does not match apex", "https://example.com/callback", RedirectErrNotAllowed},
+		{"other domain", "https://evil.com/callback", RedirectErrNotAllowed},
+		{"allowed domain as prefix", "https://example.com.evil.com/callback", RedirectErrNotAllowed},
+		{"allowed domain as suffix without dot", "https://evilexample.com/callback", RedirectErrNotAllowed},
+		{"userinfo", "https://example.com@evil.com/callback", RedirectErrInvalid},
+		{"userinfo with allowed host", "https://evil.com@app.example.com/callback", RedirectErrInvalid},
+		{"fragment", "https://evil.com#@app.example.com", RedirectErrInvalid},
+		{"scheme mismatch", "http://app.example.com/callback", RedirectErrNotAllowed},
+		{"non-default port without port pattern", "https://app.example.com:8443/callback", RedirectErrNotAllowed},
+		{"port wildcard", "http://localhost:3000/callback", ""},
+		{"port wildcard with any scheme", "https://localhost:5173/callback", ""},
+		{"port wildcard without port", "http://localhost/callback", ""},
+		{"port wildcard on other host", "http://localhost.evil.com:3000/callback", RedirectErrNotAllowed},
+		{"exact port", "http://127.0.0.1:8080/callback", ""},
+		{"other port", "http://127.0.0.1:8081/callback", RedirectErrNotAllowed},
+		{"exact URL", "https://app.example.org/callback", ""},
+		{"exact URL with other path", "https://app.example.org/callback/other", RedirectErrNotAllowed},
+		{"exact URL with query", "https://app.example.org/callback?next=/", RedirectErrNotAllowed},
+		{"relative", "/callback", RedirectErrInvalid},
+		{"scheme relative", "//evil.com/callback", RedirectErrInvalid},
+		{"javascript", "javascript:alert(1)", RedirectErrInvalid},
+		{"empty", "", RedirectErrInvalid},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := s.checkRedirectURI(ctx, tc.redirectURI)
+			if tc.code == "" {
+				if err != nil {
+					t.Fatalf("checkRedirectURI(%q): %v", tc.redirectURI, err)
+				}
+				return
+			}
+			var redirectErr *RedirectURIError
+			if !errors.As(err, &redirectErr) || redirectErr.Code != tc.code {
+				t.Fatalf("checkRedirectURI(%q): got %v, want %s", tc.redirectURI, err, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/gogf/gf/v2/util/guid"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrRefreshTokenInvalid 刷新令牌不存在或已过期
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused 检测到已轮换的刷新令牌被再次使用，整个family已被吊销
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+	// errRefreshSessionNotFound 刷新令牌的family没有未结束的会话，用于回滚轮换
+	errRefreshSessionNotFound = errors.New("no active session for refresh token family")
+)
+
+// OAuthTokens 登录或刷新后返回给客户端的令牌
+type OAuthTokens struct {
+	AccessToken  string
+	RefreshToken string    // 本服务签发的刷新令牌（Casdoor的refresh token只保存在服务端）
+	SessionToken string    // 本服务签发的本地会话令牌，其他服务可通过JWKS验证，无需访问Casdoor
+	ExpiresAt    time.Time // 访问令牌过期时间
+}
+
+// hashRefreshToken 计算刷新令牌的SHA-256摘要，数据库只保存摘要
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken 生成一个新的轮换刷新令牌及其记录（记录中只保存令牌摘要）
+func (s *CasdoorService) newRefreshToken(username, familyId string, parentId uint64, casdoorRefreshToken string) (string, *model.RefreshToken, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(bytes)
+
+	if familyId == "" {
+		familyId = guid.S()
+	}
+
+	record := &model.RefreshToken{
+		FamilyId:            familyId,
+		TokenHash:           hashRefreshToken(refreshToken),
+		Username:            username,
+		CasdoorRefreshToken: casdoorRefreshToken,
+		ParentId:            parentId,
+		ExpiresAt:           gtime.Now().Add(Token.RefreshTTL()),
+		CreatedAt:           gtime.Now(),
+	}
+	return refreshToken, record, nil
+}
+
+// issueRefreshToken 保存Casdoor的refresh token，并签发一个新的轮换刷新令牌
+func (s *CasdoorService) issueRefreshToken(ctx context.Context, username, familyId string, parentId uint64, casdoorRefreshToken string) (string, error) {
+	refreshToken, record, err := s.newRefreshToken(username, familyId, parentId, casdoorRefreshToken)
+	if err != nil {
+		return "", err
+	}
+	if err := dao.RefreshToken.Create(ctx, record); err != nil {
+		g.Log().Error(ctx, "Failed to store refresh token:", err)
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌，并轮换刷新令牌
+// 已使用过的刷新令牌再次出现时视为泄露，吊销其所在family的全部令牌
+func (s *CasdoorService) RefreshToken(ctx context.Context, refreshToken string) (*OAuthTokens, error) {
+	if refreshToken == "" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	record, err := dao.RefreshToken.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		g.Log().Warning(ctx, "Unknown refresh token:", s.safeSubstring(refreshToken, 8)+"...")
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 已使用或已吊销的令牌再次出现：吊销整个family
+	if record.UsedAt != nil || record.RevokedAt != nil {
+		s.revokeRefreshFamily(ctx, record, "reuse detected")
+		return nil, ErrRefreshTokenReused
+	}
+
+	if record.ExpiresAt != nil && record.ExpiresAt.Before(gtime.Now()) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 先向Casdoor刷新：上游暂时失败时令牌保持未使用，客户端可以重试；
+	// Casdoor明确拒绝Casdoor的refresh token时本地令牌同样不再可用，吊销整个family
+	token, err := casdoorsdk.RefreshOAuthToken(record.CasdoorRefreshToken)
+	if err != nil {
+		if isRefreshRejected(err) {
+			g.Log().Warning(ctx, "Casdoor rejected refresh token:", err)
+			s.revokeRefreshFamily(ctx, record, "rejected by Casdoor")
+			return nil, ErrRefreshTokenInvalid
+		}
+		g.Log().Error(ctx, "Failed to refresh OAuth token:", err)
+		return nil, err
+	}
+
+	// Casdoor未返回新的refresh token时继续使用原来的
+	casdoorRefreshToken := token.RefreshToken
+	if casdoorRefreshToken == "" {
+		casdoorRefreshToken = record.CasdoorRefreshToken
+	}
+
+	// 轮换前先解析新的访问令牌：解析失败时不消耗刷新令牌
+	claims, err := s.parseSignedToken(ctx, token.AccessToken)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to parse refreshed access token:", err)
+		return nil, err
+	}
+
+	newRefreshToken, next, err := s.newRefreshToken(record.Username, record.FamilyId, record.Id, casdoorRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// 原子地标记为已使用、保存新令牌并将新的访问令牌关联到会话，并发请求中只有一个能成功
+	// family没有未结束的会话时（会话已结束，或0012迁移前没有会话记录的登录）整个轮换回滚，要求重新登录
+	var session *model.UserSession
+	rotated, err := dao.RefreshToken.Rotate(ctx, record.Id, next, func(ctx context.Context) error {
+		var err error
+		if session, err = Session.Rotate(ctx, record.FamilyId, &claims.RegisteredClaims, token.AccessToken); err != nil {
+			return err
+		}
+		if session == nil {
+			return errRefreshSessionNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, errRefreshSessionNotFound) {
+		s.revokeRefreshFamily(ctx, record, "no active session")
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		g.Log().Error(ctx, "Failed to rotate refresh token:", err)
+		return nil, err
+	}
+	if !rotated {
+		s.revokeRefreshFamily(ctx, record, "reuse detected")
+		return nil, ErrRefreshTokenReused
+	}
+
+	tokens := &OAuthTokens{
+		AccessToken:  token.AccessToken,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    token.Expiry,
+	}
+
+	// 为会话签发新的本地会话令牌（失败时客户端仍可使用Casdoor访问令牌）
+	if tokens.SessionToken, err = s.refreshLocalToken(ctx, session); err != nil {
+		g.Log().Warning(ctx, "Failed to issue local session token:", err)
+	}
+
+	g.Log().Info(ctx, "Refresh token rotated for user:", record.Username, "family:", record.FamilyId)
+
+	return tokens, nil
+}
+
+// refreshLocalToken 刷新后为会话的用户重新签发本地会话令牌
+func (s *CasdoorService) refreshLocalToken(ctx context.Context, session *model.UserSession) (string, error) {
+	user, err := s.users.GetById(ctx, session.UserId)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", fmt.Errorf("user %d not found", session.UserId)
+	}
+	return s.generateLocalToken(ctx, user, session)
+}
+
+// isRefreshRejected 判断Casdoor是否明确拒绝了refresh token（invalid_grant/invalid_request），
+// 网络错误和5xx等其他错误视为上游暂时不可用
+func isRefreshRejected(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) {
+		return false
+	}
+	switch retrieveErr.ErrorCode {
+	case "invalid_grant", "invalid_request":
+		return true
+	}
+	return false
+}
+
+// revokeRefreshFamily 吊销刷新令牌所在family的全部令牌，reason记录吊销原因
+func (s *CasdoorService) revokeRefreshFamily(ctx context.Context, record *model.RefreshToken, reason string) {
+	g.Log().Warning(ctx, "Revoking refresh token family ("+reason+"):", record.FamilyId, "user:", record.Username)
+	if err := dao.RefreshToken.RevokeFamily(ctx, record.FamilyId); err != nil {
+		g.Log().Error(ctx, "Failed to revoke refresh token family:", err)
+	}
+}
+
+// revokeRefreshToken 登出时吊销刷新令牌所在的family
+func (s *CasdoorService) revokeRefreshToken(ctx context.Context, refreshToken string) {
+	record, err := dao.RefreshToken.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		g.Log().Error(ctx, "Failed to load refresh token:", err)
+		return
+	}
+	if record == nil {
+		return
+	}
+	if err := dao.RefreshToken.RevokeFamily(ctx, record.FamilyId); err != nil {
+		g.Log().Error(ctx, "Failed to revoke refresh token family:", err)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+)
+
+// TestIsRefreshRejected Casdoor明确拒绝refresh token时视为令牌无效，网络错误和5xx视为上游暂时不可用
+func TestIsRefreshRejected(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		body     string
+		rejected bool
+	}{
+		{"invalid grant", http.StatusBadRequest, `{"error":"invalid_grant","error_description":"refresh token is invalid"}`, true},
+		{"invalid request", http.StatusBadRequest, `{"error":"invalid_request"}`, true},
+		{"invalid grant with 200", http.StatusOK, `{"error":"invalid_grant"}`, true},
+		{"invalid client", http.StatusUnauthorized, `{"error":"invalid_client"}`, false},
+		{"server error", http.StatusInternalServerError, `internal error`, false},
+		{"bad gateway", http.StatusBadGateway, ``, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			casdoorsdk.InitConfig(server.URL, "client-id", "client-secret", "", "org", "app")
+			_, err := casdoorsdk.RefreshOAuthToken("casdoor-refresh-token")
+			if err == nil {
+				t.Fatal("RefreshOAuthToken: expected an error")
+			}
+			if got := isRefreshRejected(err); got != tc.rejected {
+				t.Fatalf("isRefreshRejected(%v) = %v, want %v", err, got, tc.rejected)
+			}
+		})
+	}
+
+	t.Run("network error", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+
+		casdoorsdk.InitConfig(server.URL, "client-id", "client-secret", "", "org", "app")
+		_, err := casdoorsdk.RefreshOAuthToken("casdoor-refresh-token")
+		if err == nil {
+			t.Fatal("RefreshOAuthToken: expected an error")
+		}
+		if isRefreshRejected(err) {
+			t.Fatalf("isRefreshRejected(%v) = true for a network error", err)
+		}
+	})
+}
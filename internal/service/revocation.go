@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// RevocationService 令牌吊销服务（jti黑名单 + 用户级吊销时间）
+type RevocationService struct{}
+
+var Revocation = &RevocationService{}
+
+// Init 启动过期吊销记录的清理任务
+func (s *RevocationService) Init(ctx context.Context) error {
+	go s.cleanupExpired()
+	return nil
+}
+
+// tokenId 返回令牌的吊销键：优先使用jti，缺失时使用令牌摘要
+func (s *RevocationService) tokenId(claims *jwt.RegisteredClaims, token string) string {
+	if claims.ID != "" {
+		return claims.ID
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// RevokeToken 将令牌加入黑名单，保留到令牌自然过期为止
+func (s *RevocationService) RevokeToken(ctx context.Context, claims *jwt.RegisteredClaims, token, username string) error {
+	expiresAt := gtime.Now().Add(Token.RefreshTTL())
+	if claims.ExpiresAt != nil {
+		// 已过期的令牌无需记录
+		if claims.ExpiresAt.Before(time.Now()) {
+			return nil
+		}
+		expiresAt = gtime.New(claims.ExpiresAt.Time)
+	}
+
+	jti := s.tokenId(claims, token)
+	err := dao.RevokedToken.Create(ctx, &model.RevokedToken{
+		Jti:       jti,
+		Username:  username,
+		ExpiresAt: expiresAt,
+		CreatedAt: gtime.Now(),
+	})
+	if err != nil {
+		g.Log().Error(ctx, "Failed to revoke token:", err)
+		return err
+	}
+
+	g.Log().Info(ctx, "Token revoked:", jti, "user:", username)
+	return nil
+}
+
+// RevokeAllForUser 吊销用户当前所有的访问令牌和刷新令牌
+func (s *RevocationService) RevokeAllForUser(ctx context.Context, username string) error {
+	now := gtime.Now()
+	// JWT的iat只精确到秒，吊销时间同样按整秒保存
+	err := dao.RevokedToken.SaveUserRevocation(ctx, &model.UserTokenRevocation{
+		Username:      username,
+		RevokedBefore: gtime.New(now.Time.Truncate(time.Second)),
+		UpdatedAt:     now,
+	})
+	if err != nil {
+		g.Log().Error(ctx, "Failed to revoke user tokens:", err)
+		return err
+	}
+
+	if err := dao.RefreshToken.RevokeByUsername(ctx, username); err != nil {
+		g.Log().Error(ctx, "Failed to revoke user refresh tokens:", err)
+		return err
+	}
+
+	g.Log().Info(ctx, "All tokens revoked for user:", username)
+	return nil
+}
+
+// IsRevoked 检查令牌是否在黑名单中，或签发时间早于用户级吊销时间
+func (s *RevocationService) IsRevoked(ctx context.Context, claims *jwt.RegisteredClaims, token, username string) (bool, error) {
+	revoked, err := dao.RevokedToken.Exists(ctx, s.tokenId(claims, token))
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		return true, nil
+	}
+
+	revocation, err := dao.RevokedToken.GetUserRevocation(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	if revocation == nil || revocation.RevokedBefore == nil {
+		return false, nil
+	}
+
+	// 没有iat的令牌无法判断签发时间，按已吊销处理
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	return issuedBeforeRevocation(claims.IssuedAt.Time, revocation.RevokedBefore.Time), nil
+}
+
+// issuedBeforeRevocation 判断签发时间是否不晚于用户级吊销时间
+// iat只精确到秒，吊销时间同样截断到整秒后比较；与吊销同一秒签发的令牌无法区分先后，按已吊销处理
+func issuedBeforeRevocation(issuedAt, revokedBefore time.Time) bool {
+	return !issuedAt.Truncate(time.Second).After(revokedBefore.Truncate(time.Second))
+}
+
+// cleanupExpired 定期清理已过期的吊销记录
+func (s *RevocationService) cleanupExpired() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(time.Hour) // 每小时清理一次
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := dao.RevokedToken.DeleteExpired(ctx)
+		if err != nil {
+			g.Log().Warning(ctx, "Failed to cleanup revoked tokens:", err)
+			continue
+		}
+		if deleted > 0 {
+			g.Log().Debug(ctx, "Cleaned up expired revoked tokens:", deleted)
+		}
+	}
+}
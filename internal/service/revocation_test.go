@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIssuedBeforeRevocation 秒级iat与吊销时间比较：吊销之后的整秒内签发的令牌有效，同一秒及之前签发的令牌失效
+func TestIssuedBeforeRevocation(t *testing.T) {
+	revokedAt := time.Date(2026, 1, 2, 3, 4, 5, 600_000_000, time.UTC)
+
+	cases := []struct {
+		name     string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"earlier second", revokedAt.Add(-time.Second).Truncate(time.Second), true},
+		{"same second before revocation", revokedAt.Add(-300 * time.Millisecond).Truncate(time.Second), true},
+		{"same second after revocation", revokedAt.Add(300 * time.Millisecond).Truncate(time.Second), true},
+		{"next second", revokedAt.Add(time.Second).Truncate(time.Second), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := issuedBeforeRevocation(tc.issuedAt, revokedAt); got != tc.revoked {
+				t.Fatalf("issuedBeforeRevocation(%s, %s) = %v, want %v", tc.issuedAt, revokedAt, got, tc.revoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/gogf/gf/v2/frame/g"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// casdoorApplicationType Casdoor通过client_credentials签发的令牌中，User.Type固定为application
+const casdoorApplicationType = "application"
+
+var (
+	// ErrServiceClientUnknown 客户端未在serviceClients中登记
+	ErrServiceClientUnknown = errors.New("service client is not registered")
+	// ErrServiceClientCredentials 客户端凭证被Casdoor拒绝
+	ErrServiceClientCredentials = errors.New("invalid service client credentials")
+)
+
+// ServiceClientConfig 登记的服务客户端（对应Casdoor中的一个应用）
+type ServiceClientConfig struct {
+	ClientId string   `json:"clientId"`
+	Name     string   `json:"name"`   // 日志中显示的服务名称
+	Scopes   []string `json:"scopes"` // 该服务可以使用的权限范围
+}
+
+// ServicePrincipal 以服务身份调用API的调用方
+type ServicePrincipal struct {
+	ClientId string   `json:"clientId"`
+	Name     string   `json:"name"`
+	Scopes   []string `json:"scopes"`
+}
+
+// ServiceClientService 服务间调用（OAuth2 client_credentials）认证服务
+type ServiceClientService struct {
+	clients map[string]*ServiceClientConfig
+}
+
+var ServiceClient = &ServiceClientService{}
+
+// Init 加载登记的服务客户端
+func (s *ServiceClientService) Init(ctx context.Context) error {
+	var clients []*ServiceClientConfig
+	if value, err := g.Cfg().Get(ctx, "serviceClients"); err == nil && !value.IsEmpty() {
+		if err := value.Scan(&clients); err != nil {
+			return fmt.Errorf("serviceClients 配置格式错误: %w", err)
+		}
+	}
+
+	s.clients = make(map[string]*ServiceClientConfig)
+	for _, client := range clients {
+		if client.ClientId == "" {
+			return fmt.Errorf("serviceClients 中存在未配置clientId的条目")
+		}
+		if client.Name == "" {
+			client.Name = client.ClientId
+		}
+		s.clients[client.ClientId] = client
+	}
+
+	g.Log().Info(ctx, "✅ 服务客户端配置加载完成，已登记:", len(s.clients))
+	return nil
+}
+
+// isApplicationToken 判断Casdoor令牌是否为应用（而非用户）令牌
+func (s *ServiceClientService) isApplicationToken(claims *casdoorsdk.Claims) bool {
+	return claims.User.Type == casdoorApplicationType
+}
+
+// IssueToken 代理client_credentials授权：仅允许已登记的服务客户端向Casdoor换取应用令牌
+func (s *ServiceClientService) IssueToken(ctx context.Context, clientId, clientSecret string) (*oauth2.Token, *ServicePrincipal, error) {
+	client, ok := s.clients[clientId]
+	if !ok {
+		g.Log().Warning(ctx, "Rejected client_credentials request from unregistered client:", clientId)
+		return nil, nil, ErrServiceClientUnknown
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     clientId,
+		ClientSecret: clientSecret,
+		TokenURL:     strings.TrimRight(Casdoor.config.Endpoint, "/") + "/api/login/oauth/access_token",
+		AuthStyle:    oauth2.AuthStyleInParams,
+	}
+	token, err := config.Token(ctx)
+	if err != nil {
+		g.Log().Warning(ctx, "Casdoor rejected client credentials:", client.Name, err)
+		return nil, nil, fmt.Errorf("%w: %v", ErrServiceClientCredentials, err)
+	}
+
+	// Casdoor在出错时可能以"error:"前缀的access_token返回错误信息
+	if strings.HasPrefix(token.AccessToken, "error:") {
+		g.Log().Warning(ctx, "Casdoor rejected client credentials:", client.Name, token.AccessToken)
+		return nil, nil, fmt.Errorf("%w: %s", ErrServiceClientCredentials, strings.TrimPrefix(token.AccessToken, "error:"))
+	}
+
+	g.Log().Info(ctx, "Service token issued:", client.Name, "client:", clientId)
+	return token, s.principal(client), nil
+}
+
+// authenticate 校验Casdoor应用令牌：aud必须是已登记的服务客户端
+func (s *ServiceClientService) authenticate(ctx context.Context, claims *casdoorsdk.Claims) (*Identity, error) {
+	var client *ServiceClientConfig
+	for _, audience := range claims.Audience {
+		if registered, ok := s.clients[audience]; ok {
+			client = registered
+			break
+		}
+	}
+	if client == nil {
+		g.Log().Warning(ctx, "Application token from unregistered client rejected, aud:", claims.Audience)
+		return nil, &TokenError{Code: TokenErrAudience, Message: "服务客户端未登记"}
+	}
+
+	err := validateRegisteredClaims(&claims.RegisteredClaims, &claimRules{
+		Issuers:   Casdoor.trustedIssuers(),
+		Audience:  client.ClientId,
+		ClockSkew: Token.ClockSkew(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	principal := s.principal(client)
+	return &Identity{
+		Owner:   claims.User.Owner,
+		Source:  IdentitySourceService,
+		Scopes:  principal.Scopes,
+		Service: principal,
+	}, nil
+}
+
+// principal 根据登记信息构建服务调用方
+func (s *ServiceClientService) principal(client *ServiceClientConfig) *ServicePrincipal {
+	scopes := client.Scopes
+	if scopes == nil {
+		// 未配置权限范围的服务不具有任何权限，而不是不受限
+		scopes = []string{}
+	}
+	return &ServicePrincipal{
+		ClientId: client.ClientId,
+		Name:     client.Name,
+		Scopes:   scopes,
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// sessionTouchInterval 同一IP的请求更新最近活跃时间的最小间隔
+const sessionTouchInterval = time.Minute
+
+// ErrSessionNotFound 会话不存在或已结束
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService 用户会话服务：记录登录会话，支持查看和结束会话
+type SessionService struct{}
+
+var Session = &SessionService{}
+
+// Init 启动过期会话的清理任务
+func (s *SessionService) Init(ctx context.Context) error {
+	go s.cleanupExpired()
+	return nil
+}
+
+// Record 登录成功后记录会话，IP和User-Agent取自当前请求
+// familyId为空表示没有刷新令牌，会话随访问令牌过期
+func (s *SessionService) Record(ctx context.Context, user *model.User, claims *jwt.RegisteredClaims, token, familyId string) (*model.UserSession, error) {
+	expiresAt := gtime.Now().Add(Token.RefreshTTL())
+	if familyId == "" && claims.ExpiresAt != nil {
+		expiresAt = gtime.New(claims.ExpiresAt.Time)
+	}
+
+	session := &model.UserSession{
+		UserId:     user.Id,
+		Username:   user.Username,
+		TokenId:    Revocation.tokenId(claims, token),
+		FamilyId:   familyId,
+		CreatedAt:  gtime.Now(),
+		LastSeenAt: gtime.Now(),
+		ExpiresAt:  expiresAt,
+	}
+	if r := g.RequestFromCtx(ctx); r != nil {
+		session.Ip = r.GetClientIp()
+		session.UserAgent = truncate(r.UserAgent(), 500)
+	}
+
+	if err := dao.UserSession.Create(ctx, session); err != nil {
+		g.Log().Error(ctx, "Failed to record session:", err)
+		return nil, err
+	}
+
+	g.Log().Info(ctx, "Session recorded:", session.Id, "user:", user.Username, "ip:", session.Ip)
+	return session, nil
+}
+
+// Rotate 刷新令牌轮换后，将新的访问令牌关联到会话（之前的访问令牌仍属于该会话，结束会话时一并失效）
+// 返回刷新令牌所属的会话（没有未结束的会话时返回nil）
+func (s *SessionService) Rotate(ctx context.Context, familyId string, claims *jwt.RegisteredClaims, token string) (*model.UserSession, error) {
+	session, err := dao.UserSession.UpdateTokenId(ctx, familyId, Revocation.tokenId(claims, token))
+	if err != nil {
+		g.Log().Error(ctx, "Failed to rotate session token:", err)
+		return nil, err
+	}
+	return session, nil
+}
+
+// Check 检查访问令牌所属会话：没有会话记录或会话已结束时返回错误，否则更新最近活跃时间
+// 本服务登录（浏览器回调和设备码）签发的每个访问令牌都关联到会话，没有会话记录的令牌不是经由本服务登录获得的
+func (s *SessionService) Check(ctx context.Context, claims *jwt.RegisteredClaims, token string) (*model.UserSession, error) {
+	session, err := dao.UserSession.GetByTokenId(ctx, Revocation.tokenId(claims, token))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		g.Log().Error(ctx, "Failed to load session:", err)
+		return nil, err
+	}
+	if session == nil {
+		g.Log().Warning(ctx, "Token without session rejected, subject:", claims.Subject)
+		return nil, &TokenError{Code: TokenErrSessionTerminated, Message: "会话不存在或已结束"}
+	}
+	return s.check(ctx, session)
+}
+
+// CheckById 检查本地会话令牌所属会话（令牌的sid声明），规则与Check相同
+func (s *SessionService) CheckById(ctx context.Context, id uint64) (*model.UserSession, error) {
+	session, err := dao.UserSession.GetById(ctx, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		g.Log().Error(ctx, "Failed to load session:", err)
+		return nil, err
+	}
+	if session == nil {
+		g.Log().Warning(ctx, "Local token without session rejected, session:", id)
+		return nil, &TokenError{Code: TokenErrSessionTerminated, Message: "会话不存在或已结束"}
+	}
+	return s.check(ctx, session)
+}
+
+// check 已结束的会话返回错误，否则更新最近活跃时间
+func (s *SessionService) check(ctx context.Context, session *model.UserSession) (*model.UserSession, error) {
+	if session.TerminatedAt != nil {
+		g.Log().Warning(ctx, "Terminated session rejected:", session.Id, "user:", session.Username)
+		return nil, &TokenError{Code: TokenErrSessionTerminated, Message: "会话已结束"}
+	}
+
+	s.touch(ctx, session)
+	return session, nil
+}
+
+// touch 更新最近活跃时间和IP（同一IP短时间内的请求跳过，减少写库）
+func (s *SessionService) touch(ctx context.Context, session *model.UserSession) {
+	ip := ""
+	if r := g.RequestFromCtx(ctx); r != nil {
+		ip = r.GetClientIp()
+	}
+	if session.LastSeenAt != nil && session.Ip == ip &&
+		gtime.Now().Sub(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+
+	if err := dao.UserSession.TouchLastSeen(ctx, session.Id, ip); err != nil {
+		g.Log().Warning(ctx, "Failed to update session last seen:", err)
+	}
+}
+
+// List 获取用户的活跃会话
+func (s *SessionService) List(ctx context.Context, userId uint64) ([]*model.UserSession, error) {
+	return dao.UserSession.ListActiveByUser(ctx, userId)
+}
+
+// Terminate 结束用户的指定会话，并吊销其刷新令牌（会话的所有访问令牌由Check拒绝）
+func (s *SessionService) Terminate(ctx context.Context, userId, id uint64) error {
+	session, err := dao.UserSession.Terminate(ctx, userId, id)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to terminate session:", err)
+		return err
+	}
+	if session == nil {
+		return ErrSessionNotFound
+	}
+
+	if session.FamilyId != "" {
+		if err := dao.RefreshToken.RevokeFamily(ctx, session.FamilyId); err != nil {
+			g.Log().Error(ctx, "Failed to revoke session refresh tokens:", err)
+			return err
+		}
+	}
+
+	g.Log().Info(ctx, "Session terminated:", id, "user:", session.Username)
+	return nil
+}
+
+// TerminateByToken 登出时结束访问令牌对应的会话
+func (s *SessionService) TerminateByToken(ctx context.Context, claims *jwt.RegisteredClaims, token string) error {
+	return dao.UserSession.TerminateByTokenId(ctx, Revocation.tokenId(claims, token))
+}
+
+// TerminateAll 结束用户的所有会话
+func (s *SessionService) TerminateAll(ctx context.Context, username string) error {
+	return dao.UserSession.TerminateByUsername(ctx, username)
+}
+
+// cleanupExpired 定期清理已过期的会话记录
+func (s *SessionService) cleanupExpired() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(time.Hour) // 每小时清理一次
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := dao.UserSession.DeleteExpired(ctx)
+		if err != nil {
+			g.Log().Warning(ctx, "Failed to cleanup expired sessions:", err)
+			continue
+		}
+		if deleted > 0 {
+			g.Log().Debug(ctx, "Cleaned up expired sessions:", deleted)
+		}
+	}
+}
+
+// truncate 截断字符串到指定长度（按字节，避免超出列宽）
+func truncate(value string, maxLen int) string {
+	if len(value) <= maxLen {
+		return value
+	}
+	return value[:maxLen]
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StateEntry state参数关联的数据
+type StateEntry struct {
+	ExpiresAt      time.Time `json:"expiresAt"`
+	CodeVerifier   string    `json:"codeVerifier,omitempty"`   // PKCE code_verifier，换取token时使用
+	RedirectURI    string    `json:"redirectUri,omitempty"`    // 生成state时绑定的redirect_uri
+	DeviceUserCode string    `json:"deviceUserCode,omitempty"` // 设备码登录时绑定的用户码
+	DeviceBinding  string    `json:"deviceBinding,omitempty"`  // 设备码登录时绑定浏览器的Cookie摘要，回调时校验
+	DeviceConfirm  bool      `json:"deviceConfirm,omitempty"`  // 设备码确认页的CSRF令牌（不能用作登录回调的state）
+}
+
+// expired 判断state是否已过期
+func (e *StateEntry) expired() bool {
+	return time.Now().After(e.ExpiresAt)
+}
+
+// StateStore State参数存储（用于CSRF防护）
+// 多副本部署时需使用redis或pgsql后端，保证在任意实例上都能校验state
+type StateStore interface {
+	// Save 保存state及其关联数据
+	Save(ctx context.Context, state string, entry *StateEntry) error
+	// Consume 原子地取出并删除state（一次性使用），不存在或已过期时返回nil
+	Consume(ctx context.Context, state string) (*StateEntry, error)
+	// Cleanup 清理过期的state
+	Cleanup(ctx context.Context) error
+	// Count 统计未过期的state数量，用于限制未完成的登录请求总数
+	Count(ctx context.Context) (int, error)
+}
+
+// 支持的StateStore后端
+const (
+	StateStoreMemory = "memory"
+	StateStoreRedis  = "redis"
+	StateStorePgsql  = "pgsql"
+)
+
+// newStateStore 根据配置创建StateStore
+func newStateStore(ctx context.Context, backend string) (StateStore, error) {
+	switch backend {
+	case "", StateStoreMemory:
+		return newMemoryStateStore(), nil
+	case StateStoreRedis:
+		// 启动时检查Redis连接，避免登录时才发现配置错误
+		if _, err := dao.Redis.Do(ctx, "PING"); err != nil {
+			return nil, fmt.Errorf("redis不可用: %w", err)
+		}
+		return newRedisStateStore(), nil
+	case StateStorePgsql:
+		return newPgsqlStateStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported state store backend: %s", backend)
+	}
+}
+
+// memoryStateStore 进程内state存储（仅适用于单实例部署）
+type memoryStateStore struct {
+	states map[string]*StateEntry
+	mutex  sync.Mutex
+}
+
+func newMemoryStateStore() *memoryStateStore {
+	return &memoryStateStore{
+		states: make(map[string]*StateEntry),
+	}
+}
+
+func (m *memoryStateStore) Save(ctx context.Context, state string, entry *StateEntry) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.states[state] = entry
+	return nil
+}
+
+func (m *memoryStateStore) Consume(ctx context.Context, state string) (*StateEntry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, exists := m.states[state]
+	if !exists {
+		return nil, nil
+	}
+	delete(m.states, state)
+	if entry.expired() {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Count 统计未过期的state数量（与redis和pgsql后端一致，过期未清理的不计入）
+func (m *memoryStateStore) Count(ctx context.Context) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	count := 0
+	for _, entry := range m.states {
+		if !entry.expired() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryStateStore) Cleanup(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for state, entry := range m.states {
+		if entry.expired() {
+			delete(m.states, state)
+		}
+	}
+	return nil
+}
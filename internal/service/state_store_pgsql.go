@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"encoding/json"
+
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// pgsqlStateStore 基于PostgreSQL oauth_states表的state存储
+type pgsqlStateStore struct{}
+
+func newPgsqlStateStore() *pgsqlStateStore {
+	return &pgsqlStateStore{}
+}
+
+func (p *pgsqlStateStore) Save(ctx context.Context, state string, entry *StateEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return dao.OAuthState.Create(ctx, &model.OAuthState{
+		State:     state,
+		Data:      string(data),
+		ExpiresAt: gtime.New(entry.ExpiresAt),
+		CreatedAt: gtime.Now(),
+	})
+}
+
+func (p *pgsqlStateStore) Consume(ctx context.Context, state string) (*StateEntry, error) {
+	record, err := dao.OAuthState.Consume(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, nil
+	}
+
+	var entry *StateEntry
+	if err := json.Unmarshal([]byte(record.Data), &entry); err != nil {
+		return nil, err
+	}
+	// 过期但尚未清理的state同样删除，不返回
+	if entry.expired() {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (p *pgsqlStateStore) Count(ctx context.Context) (int, error) {
+	return dao.OAuthState.CountActive(ctx)
+}
+
+func (p *pgsqlStateStore) Cleanup(ctx context.Context) error {
+	_, err := dao.OAuthState.DeleteExpired(ctx)
+	return err
+}
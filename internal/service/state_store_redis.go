@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// redisStateKeyPrefix Redis中state键的前缀
+	redisStateKeyPrefix = "oauth_state:"
+	// redisStateIndexKey 记录所有state及其过期时间的有序集合，用于统计未完成的state数量
+	redisStateIndexKey = "oauth_state_index"
+)
+
+// redisStateStore 基于Redis的state存储，过期由Redis TTL处理
+type redisStateStore struct{}
+
+func newRedisStateStore() *redisStateStore {
+	return &redisStateStore{}
+}
+
+func (r *redisStateStore) Save(ctx context.Context, state string, entry *StateEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	ttl := int64(time.Until(entry.ExpiresAt).Seconds())
+	if ttl <= 0 {
+		ttl = 1
+	}
+	if err := dao.Redis.SetEX(ctx, redisStateKeyPrefix+state, string(data), ttl); err != nil {
+		return err
+	}
+	_, err = dao.Redis.Do(ctx, "ZADD", redisStateIndexKey, entry.ExpiresAt.UnixMilli(), state)
+	return err
+}
+
+func (r *redisStateStore) Consume(ctx context.Context, state string) (*StateEntry, error) {
+	// GETDEL保证同一个state只会被一个请求取到
+	value, err := dao.Redis.GetDel(ctx, redisStateKeyPrefix+state)
+	if err == nil || errors.Is(err, dao.ErrRedisNil) {
+		if _, err := dao.Redis.Do(ctx, "ZREM", redisStateIndexKey, state); err != nil {
+			return nil, err
+		}
+	}
+	if errors.Is(err, dao.ErrRedisNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *StateEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, err
+	}
+	// 键的TTL按秒取整，最后一秒内可能仍能取到已过期的state
+	if entry.expired() {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+func (r *redisStateStore) Count(ctx context.Context) (int, error) {
+	reply, err := dao.Redis.Do(ctx, "ZCOUNT", redisStateIndexKey, time.Now().UnixMilli(), "+inf")
+	if err != nil {
+		return 0, err
+	}
+	count, _ := reply.(int64)
+	return int(count), nil
+}
+
+// Cleanup state键由Redis TTL过期，这里只清理索引中已过期的条目
+func (r *redisStateStore) Cleanup(ctx context.Context) error {
+	_, err := dao.Redis.Do(ctx, "ZREMRANGEBYSCORE", redisStateIndexKey, "-inf", time.Now().UnixMilli())
+	return err
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/gogf/gf/contrib/drivers/pgsql/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gcfg"
+	"github.com/gogf/gf/v2/util/guid"
+)
+
+// TestStateStore 对各StateStore后端运行同一套一致性用例
+// redis和pgsql后端分别需要设置TEST_REDIS_ADDRESS和TEST_DATABASE_LINK，未设置时跳过
+func TestStateStore(t *testing.T) {
+	ctx := context.Background()
+	backends := []struct {
+		name  string
+		setup func(t *testing.T) StateStore
+	}{
+		{StateStoreMemory, func(t *testing.T) StateStore { return newMemoryStateStore() }},
+		{StateStoreRedis, func(t *testing.T) StateStore {
+			address := os.Getenv("TEST_REDIS_ADDRESS")
+			if address == "" {
+				t.Skip("TEST_REDIS_ADDRESS not set")
+			}
+			adapter, err := gcfg.NewAdapterContent(fmt.Sprintf(`{"redis":{"default":{"address":%q}}}`, address))
+			if err != nil {
+				t.Fatalf("config: %v", err)
+			}
+			g.Cfg().SetAdapter(adapter)
+			return newRedisStateStore()
+		}},
+		{StateStorePgsql, func(t *testing.T) StateStore {
+			link := os.Getenv("TEST_DATABASE_LINK")
+			if link == "" {
+				t.Skip("TEST_DATABASE_LINK not set")
+			}
+			if err := gdb.SetConfig(gdb.Config{gdb.DefaultGroupName: gdb.ConfigGroup{{Link: link}}}); err != nil {
+				t.Fatalf("SetConfig: %v", err)
+			}
+			if _, err := Migrate.Up(ctx); err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			return newPgsqlStateStore()
+		}},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			testStateStore(t, backend.setup(t))
+		})
+	}
+}
+
+// testStateStore StateStore的一致性用例；共享后端中可能已有其他state，Count按增量比较
+func testStateStore(t *testing.T, store StateStore) {
+	ctx := context.Background()
+	valid := func() *StateEntry {
+		return &StateEntry{
+			ExpiresAt:    time.Now().Add(time.Minute).Truncate(time.Millisecond),
+			CodeVerifier: "verifier",
+			RedirectURI:  "https://app.example.com/callback",
+		}
+	}
+	expired := func() *StateEntry {
+		return &StateEntry{ExpiresAt: time.Now().Add(-time.Second)}
+	}
+	count := func() int {
+		t.Helper()
+		n, err := store.Count(ctx)
+		if err != nil {
+			t.Fatalf("Count: %v", err)
+		}
+		return n
+	}
+	save := func(entry *StateEntry) string {
+		t.Helper()
+		state := "test-" + guid.S()
+		if err := store.Save(ctx, state, entry); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		return state
+	}
+
+	tests := []struct {
+		name  string
+		entry func() *StateEntry // 为nil时不保存，直接消费未知的state
+		want  bool               // 第一次Consume是否返回entry
+		delta int                // Save后Count的增量
+	}{
+		{"valid", valid, true, 1},
+		{"expired", expired, false, 0},
+		{"unknown", nil, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := count()
+			state := "test-unknown-" + guid.S()
+			var entry *StateEntry
+			if tt.entry != nil {
+				entry = tt.entry()
+				state = save(entry)
+			}
+			if got := count() - base; got != tt.delta {
+				t.Fatalf("Count after Save: got +%d, want +%d", got, tt.delta)
+			}
+
+			got, err := store.Consume(ctx, state)
+			if err != nil {
+				t.Fatalf("Consume: %v", err)
+			}
+			if (got != nil) != tt.want {
+				t.Fatalf("Consume: got %+v, want entry: %v", got, tt.want)
+			}
+			if got != nil && (!got.ExpiresAt.Equal(entry.ExpiresAt) ||
+				got.CodeVerifier != entry.CodeVerifier || got.RedirectURI != entry.RedirectURI) {
+				t.Fatalf("Consume: got %+v, want %+v", got, entry)
+			}
+
+			// state只能使用一次
+			if again, err := store.Consume(ctx, state); err != nil || again != nil {
+				t.Fatalf("second Consume: want nil, nil; got %+v, %v", again, err)
+			}
+			if got := count() - base; got != 0 {
+				t.Fatalf("Count after Consume: got +%d, want +0", got)
+			}
+		})
+	}
+
+	t.Run("Cleanup", func(t *testing.T) {
+		base := count()
+		state := save(valid())
+		save(expired())
+		if err := store.Cleanup(ctx); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+		if got := count() - base; got != 1 {
+			t.Fatalf("Count after Cleanup: got +%d, want +1", got)
+		}
+		if got, err := store.Consume(ctx, state); err != nil || got == nil {
+			t.Fatalf("Consume after Cleanup: got %+v, %v", got, err)
+		}
+	})
+}
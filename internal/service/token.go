@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/util/guid"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenConfig 本地会话Token配置结构体
+type TokenConfig struct {
+	PrivateKeyPath string        // 签名私钥文件路径（PEM，支持RSA和Ed25519）
+	KeyId          string        // JWT头中的kid，留空则根据公钥自动生成
+	Issuer         string        // iss声明
+	Audience       string        // aud声明
+	TTL            time.Duration // Token有效期
+	RefreshTTL     time.Duration // 刷新令牌有效期
+	ClockSkew      time.Duration // 校验exp/nbf/iat时允许的时钟偏差
+}
+
+// LocalClaims 本地会话Token的声明
+type LocalClaims struct {
+	UserId    uint64 `json:"uid"`
+	Username  string `json:"username"`
+	Email     string `json:"email,omitempty"`
+	SessionId uint64 `json:"sid"` // 所属登录会话，会话结束后令牌随之失效
+	jwt.RegisteredClaims
+}
+
+// TokenService 本地会话Token签发与验证服务
+type TokenService struct {
+	config     *TokenConfig
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+var Token = &TokenService{}
+
+// Init 加载签名密钥并初始化Token服务
+func (s *TokenService) Init(ctx context.Context) error {
+	config, err := s.loadConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("配置加载失败: %w", err)
+	}
+
+	content, err := os.ReadFile(config.PrivateKeyPath)
+	if err != nil {
+		return fmt.Errorf("无法读取签名私钥文件 %s: %w", config.PrivateKeyPath, err)
+	}
+
+	// 优先按RSA私钥解析，失败后再尝试Ed25519
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(content); err == nil {
+		s.method = jwt.SigningMethodRS256
+		s.privateKey = rsaKey
+		s.publicKey = rsaKey.Public()
+	} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(content); err == nil {
+		signer, ok := edKey.(ed25519.PrivateKey)
+		if !ok {
+			return fmt.Errorf("不支持的Ed25519私钥类型")
+		}
+		s.method = jwt.SigningMethodEdDSA
+		s.privateKey = signer
+		s.publicKey = signer.Public()
+	} else {
+		return fmt.Errorf("签名私钥格式不支持，仅支持RSA或Ed25519 PEM私钥")
+	}
+
+	if config.KeyId == "" {
+		keyId, err := s.thumbprint()
+		if err != nil {
+			return fmt.Errorf("生成kid失败: %w", err)
+		}
+		config.KeyId = keyId
+	}
+
+	s.config = config
+
+	g.Log().Info(ctx, "✅ 本地Token服务初始化完成:")
+	g.Log().Info(ctx, "   - 签名算法:", s.method.Alg())
+	g.Log().Info(ctx, "   - Key ID:", config.KeyId)
+	g.Log().Info(ctx, "   - Issuer:", config.Issuer)
+	g.Log().Info(ctx, "   - Audience:", config.Audience)
+	g.Log().Info(ctx, "   - TTL:", config.TTL)
+	g.Log().Info(ctx, "   - Refresh TTL:", config.RefreshTTL)
+
+	return nil
+}
+
+// loadConfig 加载本地Token配置（配置文件优先，环境变量覆盖）
+func (s *TokenService) loadConfig(ctx context.Context) (*TokenConfig, error) {
+	config := &TokenConfig{
+		PrivateKeyPath: "./certs/token_jwt_key.key",
+		TTL:            7 * 24 * time.Hour,
+		RefreshTTL:     30 * 24 * time.Hour,
+		ClockSkew:      time.Minute,
+	}
+	cfg := g.Cfg()
+
+	if privateKey, err := cfg.Get(ctx, "token.privateKey"); err == nil && !privateKey.IsEmpty() {
+		config.PrivateKeyPath = privateKey.String()
+	}
+	if keyId, err := cfg.Get(ctx, "token.keyId"); err == nil && !keyId.IsEmpty() {
+		config.KeyId = keyId.String()
+	}
+	if issuer, err := cfg.Get(ctx, "token.issuer"); err == nil && !issuer.IsEmpty() {
+		config.Issuer = issuer.String()
+	}
+	if audience, err := cfg.Get(ctx, "token.audience"); err == nil && !audience.IsEmpty() {
+		config.Audience = audience.String()
+	}
+	if ttl, err := cfg.Get(ctx, "token.ttl"); err == nil && !ttl.IsEmpty() {
+		config.TTL = ttl.Duration()
+	}
+	if refreshTTL, err := cfg.Get(ctx, "token.refreshTtl"); err == nil && !refreshTTL.IsEmpty() {
+		config.RefreshTTL = refreshTTL.Duration()
+	}
+	if clockSkew, err := cfg.Get(ctx, "token.clockSkew"); err == nil && !clockSkew.IsEmpty() {
+		config.ClockSkew = clockSkew.Duration()
+	}
+
+	// 环境变量覆盖配置文件
+	if privateKey := os.Getenv("TOKEN_PRIVATE_KEY"); privateKey != "" {
+		config.PrivateKeyPath = privateKey
+	}
+	if keyId := os.Getenv("TOKEN_KEY_ID"); keyId != "" {
+		config.KeyId = keyId
+	}
+	if issuer := os.Getenv("TOKEN_ISSUER"); issuer != "" {
+		config.Issuer = issuer
+	}
+	if audience := os.Getenv("TOKEN_AUDIENCE"); audience != "" {
+		config.Audience = audience
+	}
+
+	// 未配置时沿用应用外部地址和Casdoor应用名称
+	if config.Issuer == "" && Casdoor.appConfig != nil {
+		config.Issuer = Casdoor.appConfig.ExternalUrl
+	}
+	if config.Audience == "" && Casdoor.config != nil {
+		config.Audience = Casdoor.config.ApplicationName
+	}
+
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("token issuer 不能为空")
+	}
+	if config.Audience == "" {
+		return nil, fmt.Errorf("token audience 不能为空")
+	}
+	if config.TTL <= 0 {
+		return nil, fmt.Errorf("token ttl 必须大于0")
+	}
+	if config.RefreshTTL <= 0 {
+		return nil, fmt.Errorf("token refreshTtl 必须大于0")
+	}
+
+	return config, nil
+}
+
+// thumbprint 根据公钥DER编码生成稳定的kid
+func (s *TokenService) thumbprint() (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(s.publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:16], nil
+}
+
+// ClockSkew 返回校验令牌时间声明时允许的时钟偏差
+func (s *TokenService) ClockSkew() time.Duration {
+	if s.config == nil {
+		return time.Minute
+	}
+	return s.config.ClockSkew
+}
+
+// RefreshTTL 返回刷新令牌有效期
+func (s *TokenService) RefreshTTL() time.Duration {
+	if s.config == nil {
+		return 30 * 24 * time.Hour
+	}
+	return s.config.RefreshTTL
+}
+
+// Issue 为用户的登录会话签发本地会话Token
+func (s *TokenService) Issue(ctx context.Context, user *model.User, sessionId uint64) (string, *LocalClaims, error) {
+	if s.config == nil {
+		return "", nil, fmt.Errorf("token service not initialized")
+	}
+
+	now := time.Now()
+	claims := &LocalClaims{
+		UserId:    user.Id,
+		Username:  user.Username,
+		Email:     user.Email,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.config.Issuer,
+			Subject:   user.Username,
+			Audience:  jwt.ClaimStrings{s.config.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.TTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        guid.S(),
+		},
+	}
+
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = s.config.KeyId
+
+	signed, err := token.SignedString(s.privateKey)
+	if err != nil {
+		g.Log().Error(ctx, "Failed to sign local token:", err)
+		return "", nil, err
+	}
+
+	g.Log().Debug(ctx, "Issued local token:", claims.ID, "for user:", user.Username)
+	return signed, claims, nil
+}
+
+// IsLocalToken 判断token是否由本服务签发（根据kid判断，不校验签名）
+func (s *TokenService) IsLocalToken(token string) bool {
+	if s.config == nil {
+		return false
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &LocalClaims{})
+	if err != nil {
+		return false
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid == s.config.KeyId
+}
+
+// Verify 验证本地会话Token的签名及iss/aud/exp/nbf声明
+func (s *TokenService) Verify(ctx context.Context, token string) (*LocalClaims, error) {
+	if s.config == nil {
+		return nil, fmt.Errorf("token service not initialized")
+	}
+
+	// 时间声明由validateRegisteredClaims统一校验（支持时钟偏差）
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{s.method.Alg()}), jwt.WithoutClaimsValidation())
+	parsed, err := parser.ParseWithClaims(token, &LocalClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if kid, _ := t.Header["kid"].(string); kid != s.config.KeyId {
+			return nil, fmt.Errorf("unknown key id: %v", t.Header["kid"])
+		}
+		return s.publicKey, nil
+	})
+	if err != nil {
+		g.Log().Warning(ctx, "Failed to verify local token:", err)
+		return nil, newParseError(err)
+	}
+
+	claims, ok := parsed.Claims.(*LocalClaims)
+	if !ok || !parsed.Valid {
+		return nil, &TokenError{Code: TokenErrMalformed, Message: "令牌格式错误"}
+	}
+
+	err = validateRegisteredClaims(&claims.RegisteredClaims, &claimRules{
+		Issuers:   []string{s.config.Issuer},
+		Audience:  s.config.Audience,
+		ClockSkew: s.config.ClockSkew,
+	})
+	if err != nil {
+		g.Log().Warning(ctx, "Local token claims rejected:", err)
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// JWKS 返回本地签名公钥的JWK Set，供其他服务验证本地Token
+func (s *TokenService) JWKS() g.Map {
+	if s.config == nil {
+		return g.Map{"keys": []g.Map{}}
+	}
+
+	key := g.Map{
+		"kid": s.config.KeyId,
+		"alg": s.method.Alg(),
+		"use": "sig",
+	}
+	switch pub := s.publicKey.(type) {
+	case *rsa.PublicKey:
+		key["kty"] = "RSA"
+		key["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		key["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		key["kty"] = "OKP"
+		key["crv"] = "Ed25519"
+		key["x"] = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return g.Map{"keys": []g.Map{key}}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+	"errors"
+	"fmt"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserEmailTaken 邮箱已被其他用户使用
+	ErrUserEmailTaken = errors.New("email is used by another user")
+)
+
+// UserAdminService 管理员用户管理：查询、修改资料、暂停和恢复用户
+type UserAdminService struct{}
+
+var UserAdmin = &UserAdminService{}
+
+// List 按条件分页查询用户
+func (s *UserAdminService) List(ctx context.Context, req *model.UserListReq) ([]*model.User, int, error) {
+	return Casdoor.users.List(ctx, req)
+}
+
+// Get 获取单个用户
+func (s *UserAdminService) Get(ctx context.Context, id uint64) (*model.User, error) {
+	user, err := Casdoor.users.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Update 修改用户资料；资料以Casdoor为准，先写入Casdoor再更新本地，避免下次同步时被覆盖
+func (s *UserAdminService) Update(ctx context.Context, id uint64, req *model.UserUpdateReq) (*model.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *user
+	if req.DisplayName != nil {
+		user.DisplayName = *req.DisplayName
+	}
+	if req.Email != nil {
+		user.Email = *req.Email
+	}
+	if req.Phone != nil {
+		user.Phone = *req.Phone
+	}
+	if req.Avatar != nil {
+		user.Avatar = *req.Avatar
+	}
+
+	changed := profileChanges(&before, user)
+	if len(changed) == 0 {
+		return user, nil
+	}
+
+	if user.Email != before.Email {
+		existing, err := Casdoor.users.GetByEmail(ctx, user.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.Id != user.Id {
+			return nil, ErrUserEmailTaken
+		}
+	}
+
+	if err := s.updateCasdoorUser(ctx, user, changed); err != nil {
+		return nil, err
+	}
+
+	user.UpdatedAt = gtime.Now()
+	if err := Casdoor.users.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: model.AuditEventUserUpdated,
+		UserId:    user.Id,
+		Username:  user.Username,
+		Success:   true,
+		Detail:    g.Map{"fields": changed, "admin": adminName(ctx)},
+	})
+	return user, nil
+}
+
+// Suspend 暂停正常或待验证状态的用户，并使其所有令牌和会话失效
+func (s *UserAdminService) Suspend(ctx context.Context, id uint64, reason string) (*model.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := UserStatus.Transition(ctx, user, model.UserStatusSuspended, reason, model.UserStatusSourceAdmin); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Reactivate 恢复被暂停的用户（在Casdoor中已删除的用户不能恢复）
+func (s *UserAdminService) Reactivate(ctx context.Context, id uint64, reason string) (*model.User, error) {
+	user, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != model.UserStatusSuspended {
+		return nil, ErrUserStatusConflict
+	}
+	if err := UserStatus.Transition(ctx, user, model.UserStatusActive, reason, model.UserStatusSourceAdmin); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// StatusHistory 获取用户的状态变更记录
+func (s *UserAdminService) StatusHistory(ctx context.Context, id uint64) ([]*model.UserStatusChange, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return UserStatus.History(ctx, id)
+}
+
+// updateCasdoorUser 将修改后的资料写入Casdoor
+func (s *UserAdminService) updateCasdoorUser(ctx context.Context, user *model.User, changed []string) error {
+	casdoorUser, err := Casdoor.GetUserInfo(ctx, user.Username)
+	if err != nil {
+		return fmt.Errorf("failed to get casdoor user: %w", err)
+	}
+	if casdoorUser == nil || (user.CasdoorId != "" && casdoorUser.Id != user.CasdoorId) {
+		return fmt.Errorf("casdoor user %s not found", user.Username)
+	}
+
+	// Casdoor按列名更新，只提交修改过的字段
+	columns := make([]string, 0, len(changed))
+	for _, field := range changed {
+		switch field {
+		case "displayName":
+			casdoorUser.DisplayName = user.DisplayName
+			columns = append(columns, "display_name")
+		case "email":
+			casdoorUser.Email = user.Email
+			columns = append(columns, "email")
+		case "phone":
+			casdoorUser.Phone = user.Phone
+			columns = append(columns, "phone")
+		case "avatar":
+			casdoorUser.Avatar = user.Avatar
+			columns = append(columns, "avatar")
+		}
+	}
+
+	ok, err := casdoorsdk.UpdateUserForColumns(casdoorUser, columns)
+	if err != nil {
+		return fmt.Errorf("failed to update casdoor user: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("casdoor rejected the update of user %s", user.Username)
+	}
+	return nil
+}
+
+// adminName 当前操作的管理员，记录在审计事件中
+func adminName(ctx context.Context) string {
+	if identity := currentIdentity(ctx); identity != nil {
+		return identity.Name()
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"errors"
+	"fmt"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+// ErrUserStatusConflict 用户当前状态不允许该操作（如暂停已暂停的用户）
+var ErrUserStatusConflict = errors.New("user status does not allow this action")
+
+// 用户状态导致拒绝访问时返回给客户端的错误码
+const (
+	UserStatusErrSuspended = "account_suspended"
+	UserStatusErrDeleted   = "account_deleted"
+	UserStatusErrPending   = "account_pending_verification"
+)
+
+// UserStatusError 用户状态不允许登录或访问接口
+type UserStatusError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *UserStatusError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// AsUserStatusError 从错误链中提取UserStatusError
+func AsUserStatusError(err error) *UserStatusError {
+	var statusErr *UserStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr
+	}
+	return nil
+}
+
+// CheckUserStatus 检查用户是否允许登录和访问接口，不允许时返回UserStatusError
+func CheckUserStatus(user *model.User) error {
+	switch user.Status {
+	case model.UserStatusSuspended:
+		return &UserStatusError{Status: user.Status, Code: UserStatusErrSuspended, Message: "账号已被暂停，请联系管理员"}
+	case model.UserStatusDeleted:
+		return &UserStatusError{Status: user.Status, Code: UserStatusErrDeleted, Message: "账号已被删除"}
+	case model.UserStatusPending:
+		return &UserStatusError{Status: user.Status, Code: UserStatusErrPending, Message: "邮箱尚未验证，请验证后重新登录"}
+	}
+	return nil
+}
+
+// UserStatusService 用户状态变更：所有状态变更都经过此服务，保证按状态机流转并留下记录
+type UserStatusService struct{}
+
+var UserStatus = &UserStatusService{}
+
+// Transition 将用户变更为目标状态，记录变更历史和审计事件
+// 变更为暂停或删除时同时使用户的所有令牌和会话失效；并发变更导致当前状态已不同时返回ErrUserStatusConflict
+func (s *UserStatusService) Transition(ctx context.Context, user *model.User, to int, reason, source string) error {
+	from := user.Status
+	if !model.CanTransitionUserStatus(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrUserStatusConflict, model.UserStatusName(from), model.UserStatusName(to))
+	}
+
+	ok, err := Casdoor.users.UpdateStatus(ctx, user.Id, from, to, reason)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+	if !ok {
+		return ErrUserStatusConflict
+	}
+	user.Status = to
+	user.StatusReason = reason
+	user.StatusChangedAt = gtime.Now()
+
+	// 状态已生效，记录失败不应阻止后续的令牌吊销
+	actor := adminName(ctx)
+	if err := dao.UserStatusHistory.Create(ctx, &model.UserStatusChange{
+		UserId:     user.Id,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		Source:     source,
+		Actor:      actor,
+		CreatedAt:  user.StatusChangedAt,
+	}); err != nil {
+		g.Log().Error(ctx, "Failed to record user status change:", user.Username, err)
+	}
+
+	if to == model.UserStatusSuspended || to == model.UserStatusDeleted {
+		if err := Casdoor.revokeUserAccess(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	Audit.Record(ctx, &model.AuditEvent{
+		EventType: userStatusAuditEvent(from, to),
+		UserId:    user.Id,
+		Username:  user.Username,
+		Success:   true,
+		Reason:    reason,
+		Detail: g.Map{
+			"from":   model.UserStatusName(from),
+			"to":     model.UserStatusName(to),
+			"source": source,
+			"actor":  actor,
+		},
+	})
+	g.Log().Infof(ctx, "User %s status changed: %s -> %s (%s)", user.Username, model.UserStatusName(from), model.UserStatusName(to), source)
+	return nil
+}
+
+// History 获取用户的状态变更记录（最近的在前）
+func (s *UserStatusService) History(ctx context.Context, userId uint64) ([]*model.UserStatusChange, error) {
+	return dao.UserStatusHistory.ListByUser(ctx, userId)
+}
+
+// userStatusAuditEvent 状态变更对应的审计事件类型
+func userStatusAuditEvent(from, to int) string {
+	switch to {
+	case model.UserStatusSuspended:
+		return model.AuditEventUserSuspended
+	case model.UserStatusDeleted:
+		return model.AuditEventUserDeactivated
+	}
+	if from == model.UserStatusPending {
+		return model.AuditEventUserActivated
+	}
+	return model.AuditEventUserReactivated
+}
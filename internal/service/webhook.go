@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/dao"
+	"context-id-backend/internal/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/gtime"
+)
+
+const (
+	// WebhookTokenHeader 共享密钥头，在Casdoor的Webhook中配置为固定请求头（值为密钥）
+	WebhookTokenHeader = "X-Webhook-Token"
+	// WebhookSignatureHeader 请求签名头，格式为 sha256=<hex(HMAC-SHA256(secret, timestamp + "." + body))>，经签名代理转发时使用
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	// WebhookTimestampHeader 签名时间戳头（Unix秒）
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// webhookSourceCasdoor Casdoor事件来源
+	webhookSourceCasdoor = "casdoor"
+	// webhookRetention 已处理事件的保留时间，需大于签名时间容差
+	webhookRetention = 7 * 24 * time.Hour
+)
+
+// Casdoor Webhook中与用户生命周期相关的事件
+const (
+	casdoorActionSignup     = "signup"
+	casdoorActionAddUser    = "add-user"
+	casdoorActionUpdateUser = "update-user"
+	casdoorActionDeleteUser = "delete-user"
+	casdoorActionLogin      = "login"
+)
+
+var (
+	// ErrWebhookDisabled 未配置Webhook密钥
+	ErrWebhookDisabled = errors.New("webhook receiver is disabled")
+	// ErrWebhookSignature 密钥或签名缺失、错误，或事件时间超出容差
+	ErrWebhookSignature = errors.New("invalid webhook signature")
+	// ErrWebhookPayload 无法解析的事件内容
+	ErrWebhookPayload = errors.New("invalid webhook payload")
+)
+
+// WebhookConfig Webhook接收配置结构体
+type WebhookConfig struct {
+	Secret    string        // 共享密钥（X-Webhook-Token或HMAC签名密钥），留空则不接收Webhook
+	Tolerance time.Duration // 事件时间（签名时间戳或事件的createdTime）允许的偏差，超出视为重放
+}
+
+// WebhookResult Webhook事件的处理结果
+type WebhookResult struct {
+	EventId   string `json:"eventId"`
+	Action    string `json:"action"`
+	Status    string `json:"status"`    // processed / ignored
+	Duplicate bool   `json:"duplicate"` // 事件已处理过（重复投递）
+}
+
+// WebhookService 接收Casdoor的Webhook，将用户注册、资料变更、删除同步到本地用户
+type WebhookService struct {
+	config *WebhookConfig
+}
+
+var Webhook = &WebhookService{}
+
+// Init 加载Webhook配置
+func (s *WebhookService) Init(ctx context.Context) error {
+	config := &WebhookConfig{
+		Tolerance: 5 * time.Minute,
+	}
+	cfg := g.Cfg()
+
+	if secret, err := cfg.Get(ctx, "webhook.secret"); err == nil && !secret.IsEmpty() {
+		config.Secret = secret.String()
+	}
+	if tolerance, err := cfg.Get(ctx, "webhook.tolerance"); err == nil && !tolerance.IsEmpty() {
+		config.Tolerance = tolerance.Duration()
+	}
+
+	// 环境变量覆盖配置文件
+	if secret := os.Getenv("CASDOOR_WEBHOOK_SECRET"); secret != "" {
+		config.Secret = secret
+	}
+
+	s.config = config
+	if config.Secret == "" {
+		g.Log().Info(ctx, "Casdoor Webhook未启用（未配置webhook.secret）")
+		return nil
+	}
+
+	if len(config.Secret) < 32 {
+		return fmt.Errorf("webhook.secret 至少需要32个字符")
+	}
+	if config.Tolerance <= 0 || config.Tolerance >= webhookRetention {
+		return fmt.Errorf("webhook.tolerance 必须大于0且小于%s", webhookRetention)
+	}
+
+	// 启动事件记录清理goroutine
+	go s.cleanupEvents()
+
+	g.Log().Info(ctx, "✅ Casdoor Webhook已启用:")
+	g.Log().Info(ctx, "   - Tolerance:", config.Tolerance)
+	return nil
+}
+
+// Enabled 是否接收Webhook
+func (s *WebhookService) Enabled() bool {
+	return s.config != nil && s.config.Secret != ""
+}
+
+// Verify 校验请求来源，时间超出容差的请求视为重放
+// Casdoor直接投递时携带X-Webhook-Token（Casdoor的Webhook只能附加固定请求头），以事件的createdTime防重放；
+// 经签名代理转发时携带时间戳和HMAC签名
+func (s *WebhookService) Verify(token, timestamp, signature string, body []byte) error {
+	if !s.Enabled() {
+		return ErrWebhookDisabled
+	}
+	if signature != "" {
+		return s.verifySignature(timestamp, signature, body)
+	}
+	if token == "" || !hmac.Equal([]byte(token), []byte(s.config.Secret)) {
+		return ErrWebhookSignature
+	}
+
+	var record struct {
+		CreatedTime string `json:"createdTime"`
+	}
+	if err := json.Unmarshal(body, &record); err != nil {
+		return ErrWebhookPayload
+	}
+	createdAt, err := time.Parse(time.RFC3339, record.CreatedTime)
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	return s.checkSkew(createdAt)
+}
+
+// verifySignature 校验签名代理附加的时间戳和HMAC签名
+func (s *WebhookService) verifySignature(timestamp, signature string, body []byte) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	if err := s.checkSkew(time.Unix(seconds, 0)); err != nil {
+		return err
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	mac := hmac.New(sha256.New, []byte(s.config.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// checkSkew 事件时间与当前时间的偏差超出容差时视为重放
+func (s *WebhookService) checkSkew(at time.Time) error {
+	skew := time.Since(at)
+	if skew > s.config.Tolerance || skew < -s.config.Tolerance {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// HandleCasdoor 处理Casdoor的Webhook事件（调用前需先通过Verify）
+// 同一事件ID只处理一次；处理失败时删除事件记录，发送方重试时会重新处理
+func (s *WebhookService) HandleCasdoor(ctx context.Context, body []byte) (*WebhookResult, error) {
+	var record casdoorsdk.Record
+	if err := json.Unmarshal(body, &record); err != nil {
+		return nil, ErrWebhookPayload
+	}
+
+	eventId := record.Name
+	if eventId == "" && record.Id != 0 {
+		eventId = strconv.Itoa(record.Id)
+	}
+	if eventId == "" || record.Action == "" {
+		return nil, ErrWebhookPayload
+	}
+
+	result := &WebhookResult{EventId: eventId, Action: record.Action}
+
+	// 只处理本应用所属组织的事件
+	if record.Organization != "" && record.Organization != Casdoor.config.OrganizationName {
+		result.Status = model.WebhookEventIgnored
+		return result, nil
+	}
+
+	event := &model.WebhookEvent{
+		EventId:    eventId,
+		Source:     webhookSourceCasdoor,
+		Action:     truncate(record.Action, 100),
+		Username:   truncate(record.User, 100),
+		Status:     model.WebhookEventProcessing,
+		ReceivedAt: gtime.Now(),
+	}
+	created, err := dao.WebhookEvent.Create(ctx, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+	if !created {
+		result.Duplicate = true
+		return result, nil
+	}
+
+	result.Status, err = s.applyCasdoor(ctx, &record)
+	if err != nil {
+		if deleteErr := dao.WebhookEvent.Delete(ctx, event.Id); deleteErr != nil {
+			g.Log().Warning(ctx, "Failed to release webhook event:", eventId, deleteErr)
+		}
+		return nil, err
+	}
+
+	if err := dao.WebhookEvent.UpdateStatus(ctx, event.Id, result.Status); err != nil {
+		g.Log().Warning(ctx, "Failed to update webhook event status:", eventId, err)
+	}
+	return result, nil
+}
+
+// applyCasdoor 将事件应用到本地用户，返回处理结果
+func (s *WebhookService) applyCasdoor(ctx context.Context, record *casdoorsdk.Record) (string, error) {
+	switch record.Action {
+	case casdoorActionSignup, casdoorActionAddUser, casdoorActionUpdateUser, casdoorActionLogin:
+		casdoorUser, err := s.eventUser(ctx, record, true)
+		if err != nil {
+			return "", err
+		}
+		if casdoorUser == nil {
+			return model.WebhookEventIgnored, nil
+		}
+		if _, err := Casdoor.SyncUser(ctx, casdoorUser); err != nil {
+			return "", fmt.Errorf("failed to sync user: %w", err)
+		}
+		return model.WebhookEventProcessed, nil
+
+	case casdoorActionDeleteUser:
+		// 用户已从Casdoor删除，只能使用事件中携带的用户信息
+		casdoorUser, err := s.eventUser(ctx, record, false)
+		if err != nil {
+			return "", err
+		}
+		if casdoorUser == nil {
+			return model.WebhookEventIgnored, nil
+		}
+		return s.deactivateUser(ctx, casdoorUser)
+
+	default:
+		return model.WebhookEventIgnored, nil
+	}
+}
+
+// eventUser 获取事件对应的Casdoor用户：优先使用事件携带的用户信息，
+// 其次解析事件对象，fetch为true时最后按用户名向Casdoor查询
+func (s *WebhookService) eventUser(ctx context.Context, record *casdoorsdk.Record, fetch bool) (*casdoorsdk.User, error) {
+	if record.ExtendedUser != nil && record.ExtendedUser.Name != "" {
+		if record.ExtendedUser.Owner == "" || record.ExtendedUser.Owner == Casdoor.config.OrganizationName {
+			return record.ExtendedUser, nil
+		}
+		return nil, nil
+	}
+
+	if record.Object != "" {
+		var user casdoorsdk.User
+		if err := json.Unmarshal([]byte(record.Object), &user); err == nil && user.Name != "" {
+			if user.Owner == "" || user.Owner == Casdoor.config.OrganizationName {
+				return &user, nil
+			}
+			return nil, nil
+		}
+	}
+
+	if !fetch || record.User == "" {
+		return nil, nil
+	}
+	user, err := Casdoor.GetUserInfo(ctx, record.User)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from casdoor: %w", err)
+	}
+	if user == nil || user.Name == "" {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// deactivateUser 停用Casdoor中已删除的用户，并使其所有令牌和会话失效
+func (s *WebhookService) deactivateUser(ctx context.Context, casdoorUser *casdoorsdk.User) (string, error) {
+	user, err := Casdoor.findLocalUser(ctx, casdoorUser)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return model.WebhookEventIgnored, nil
+	}
+
+	if err := Casdoor.deactivateUser(ctx, user, "deleted in casdoor", model.UserStatusSourceWebhook); err != nil {
+		return "", err
+	}
+	return model.WebhookEventProcessed, nil
+}
+
+// cleanupEvents 定期清理超过保留时间的事件记录
+// 事件时间超出容差的请求会被拒绝，因此清理后的事件ID不会被重放
+func (s *WebhookService) cleanupEvents() {
+	ctx := gctx.New()
+	ticker := time.NewTicker(24 * time.Hour) // 每天清理一次
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deleted, err := dao.WebhookEvent.DeleteBefore(ctx, gtime.Now().Add(-webhookRetention))
+		if err != nil {
+			g.Log().Warning(ctx, "Failed to cleanup webhook events:", err)
+			continue
+		}
+		if deleted > 0 {
+			g.Log().Debug(ctx, "Cleaned up webhook events:", deleted)
+		}
+	}
+}
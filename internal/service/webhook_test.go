@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"context-id-backend/internal/model"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/gogf/gf/contrib/drivers/pgsql/v2"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+const webhookTestSecret = "0123456789abcdef0123456789abcdef"
+
+// webhookTestBody 生成createdTime为指定时间的Casdoor事件
+func webhookTestBody(createdAt time.Time) []byte {
+	return []byte(`{"name":"event-1","action":"update-user","createdTime":"` + createdAt.Format(time.RFC3339) + `"}`)
+}
+
+// webhookTestSignature 按签名代理的格式计算签名
+func webhookTestSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestWebhookVerify 固定请求头密钥和HMAC签名两种校验方式，以及超出时间容差的重放
+func TestWebhookVerify(t *testing.T) {
+	s := &WebhookService{config: &WebhookConfig{Secret: webhookTestSecret, Tolerance: 5 * time.Minute}}
+
+	now := time.Now()
+	body := webhookTestBody(now)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	oldTimestamp := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+
+	cases := []struct {
+		name      string
+		token     string
+		timestamp string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{"token", webhookTestSecret, "", "", body, nil},
+		{"token missing", "", "", "", body, ErrWebhookSignature},
+		{"token wrong", "wrong-secret", "", "", body, ErrWebhookSignature},
+		{"token with old event", webhookTestSecret, "", "", webhookTestBody(now.Add(-10 * time.Minute)), ErrWebhookSignature},
+		{"token with future event", webhookTestSecret, "", "", webhookTestBody(now.Add(10 * time.Minute)), ErrWebhookSignature},
+		{"token without createdTime", webhookTestSecret, "", "", []byte(`{"name":"event-1"}`), ErrWebhookSignature},
+		{"token with invalid json", webhookTestSecret, "", "", []byte(`{`), ErrWebhookPayload},
+		{"signature", "", timestamp, webhookTestSignature(webhookTestSecret, timestamp, body), body, nil},
+		{"signature with other secret", "", timestamp, webhookTestSignature("other-secret", timestamp, body), body, ErrWebhookSignature},
+		{"signature with modified body", "", timestamp, webhookTestSignature(webhookTestSecret, timestamp, body), append([]byte(" "), body...), ErrWebhookSignature},
+		{"signature with other timestamp", "", oldTimestamp, webhookTestSignature(webhookTestSecret, timestamp, body), body, ErrWebhookSignature},
+		{"signature with old timestamp", "", oldTimestamp, webhookTestSignature(webhookTestSecret, oldTimestamp, body), body, ErrWebhookSignature},
+		{"signature with invalid timestamp", "", "now", webhookTestSignature(webhookTestSecret, "now", body), body, ErrWebhookSignature},
+		{"signature not hex", "", timestamp, "sha256=zz", body, ErrWebhookSignature},
+		{"invalid signature with valid token", webhookTestSecret, timestamp, "sha256=00", body, ErrWebhookSignature},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := s.Verify(tc.token, tc.timestamp, tc.signature, tc.body); !errors.Is(err, tc.want) {
+				t.Fatalf("Verify: got %v, want %v", err, tc.want)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := &WebhookService{config: &WebhookConfig{}}
+		if err := disabled.Verify(webhookTestSecret, "", "", body); !errors.Is(err, ErrWebhookDisabled) {
+			t.Fatalf("Verify: got %v, want ErrWebhookDisabled", err)
+		}
+	})
+}
+
+// TestWebhookHandleCasdoorDuplicate 同一事件重复投递只处理一次，需设置TEST_DATABASE_LINK指向可清空的测试库
+func TestWebhookHandleCasdoorDuplicate(t *testing.T) {
+	link := os.Getenv("TEST_DATABASE_LINK")
+	if link == "" {
+		t.Skip("TEST_DATABASE_LINK not set")
+	}
+
+	ctx := context.Background()
+	if err := gdb.SetConfig(gdb.Config{gdb.DefaultGroupName: gdb.ConfigGroup{{Link: link}}}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	if _, err := Migrate.Up(ctx); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if _, err := g.DB().Exec(ctx, "TRUNCATE webhook_events RESTART IDENTITY"); err != nil {
+		t.Fatalf("TRUNCATE webhook_events: %v", err)
+	}
+
+	config := Casdoor.config
+	Casdoor.config = &CasdoorConfig{OrganizationName: "org"}
+	defer func() { Casdoor.config = config }()
+
+	s := &WebhookService{config: &WebhookConfig{Secret: webhookTestSecret, Tolerance: 5 * time.Minute}}
+	// 不涉及用户的事件只记录、不处理，便于单独验证去重
+	body := []byte(`{"name":"event-duplicate","organization":"org","action":"update-application","createdTime":"` + time.Now().Format(time.RFC3339) + `"}`)
+
+	first, err := s.HandleCasdoor(ctx, body)
+	if err != nil {
+		t.Fatalf("HandleCasdoor: %v", err)
+	}
+	if first.Duplicate || first.Status != model.WebhookEventIgnored {
+		t.Fatalf("first delivery: got %+v", first)
+	}
+
+	second, err := s.HandleCasdoor(ctx, body)
+	if err != nil {
+		t.Fatalf("HandleCasdoor: %v", err)
+	}
+	if !second.Duplicate || second.EventId != "event-duplicate" {
+		t.Fatalf("second delivery: got %+v, want duplicate", second)
+	}
+
+	count, err := g.DB().Model("webhook_events").Where("event_id", "event-duplicate").Count()
+	if err != nil {
+		t.Fatalf("count events: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("webhook_events: got %d rows, want 1", count)
+	}
+}
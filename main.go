@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"context-id-backend/internal/router"
 	"context-id-backend/internal/service"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
 
 	"github.com/gogf/gf/v2/frame/g"
 	"github.com/gogf/gf/v2/net/ghttp"
@@ -22,9 +27,24 @@ func main() {
 	// 设置配置文件路径
 	g.Cfg().GetAdapter().(*gcfg.AdapterFile).SetPath("conf")
 
+	// 命令行子命令：migrate status|up|down|redo 管理数据库迁移后退出（不需要初始化其他服务）
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(ctx, os.Args[2:]))
+	}
+
 	// 初始化服务
 	service.Init(ctx)
 
+	// 命令行子命令：reconcile [--dry-run] 立即执行一次Casdoor用户对账后退出
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		os.Exit(runReconcile(ctx, os.Args[2:]))
+	}
+
+	// 注册定时用户对账任务
+	if err := service.Reconcile.Schedule(ctx); err != nil {
+		g.Log().Fatal(ctx, "Failed to schedule user reconciliation:", err)
+	}
+
 	s := g.Server()
 
 	// 设置服务器地址为8080端口
@@ -40,3 +60,81 @@ func main() {
 
 	s.Run()
 }
+
+// runMigrate 执行migrate子命令，返回进程退出码
+func runMigrate(ctx context.Context, args []string) int {
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "status":
+		statuses, err := service.Migrate.Status(ctx)
+		if err != nil {
+			g.Log().Error(ctx, "Failed to get migration status:", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.String()
+			}
+			if status.Missing {
+				state += " (missing in this build)"
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, state)
+		}
+	case "up":
+		applied, err := service.Migrate.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied  %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			g.Log().Error(ctx, "Migration failed:", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		migration, err := service.Migrate.Down(ctx)
+		if err != nil {
+			g.Log().Error(ctx, "Revert failed:", err)
+			return 1
+		}
+		fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+	case "redo":
+		migration, err := service.Migrate.Redo(ctx)
+		if err != nil {
+			g.Log().Error(ctx, "Redo failed:", err)
+			return 1
+		}
+		fmt.Printf("redone   %04d_%s\n", migration.Version, migration.Name)
+	default:
+		fmt.Fprintln(os.Stderr, "usage: main migrate [status|up|down|redo]")
+		return 2
+	}
+	return 0
+}
+
+// runReconcile 执行reconcile子命令，将对账结果以JSON输出到标准输出，返回进程退出码
+func runReconcile(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "只统计差异，不修改本地用户")
+	_ = flags.Parse(args)
+
+	report, err := service.Reconcile.Run(ctx, *dryRun)
+	if err != nil {
+		g.Log().Error(ctx, "User reconciliation failed:", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		g.Log().Error(ctx, "Failed to write reconciliation report:", err)
+		return 1
+	}
+	return 0
+}
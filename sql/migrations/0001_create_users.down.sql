@@ -0,0 +1,4 @@
+-- 删除用户表及更新时间触发器
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
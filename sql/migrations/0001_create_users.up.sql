@@ -1,12 +1,7 @@
--- 创建数据库
-CREATE DATABASE IF NOT EXISTS contextid;
-
--- 使用数据库
-\c contextid;
-
 -- 创建用户表
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
+    casdoor_id VARCHAR(100) UNIQUE,
     username VARCHAR(100) UNIQUE NOT NULL,
     email VARCHAR(255) UNIQUE NOT NULL,
     display_name VARCHAR(100),
@@ -21,6 +16,9 @@ CREATE TABLE IF NOT EXISTS users (
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 
+-- 已有数据库补充casdoor_id列（用户以Casdoor的稳定ID关联，用户名可能被修改）
+ALTER TABLE users ADD COLUMN IF NOT EXISTS casdoor_id VARCHAR(100) UNIQUE;
+
 -- 创建更新时间触发器
 CREATE OR REPLACE FUNCTION update_updated_at_column()
 RETURNS TRIGGER AS $$
@@ -30,5 +28,7 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+-- 由init.sql创建的旧数据库已有该触发器，先删除再创建
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,33 @@
+-- 创建刷新令牌表（Casdoor的refresh token只保存在服务端，客户端持有轮换令牌）
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    casdoor_refresh_token TEXT NOT NULL,
+    parent_id INTEGER DEFAULT 0,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_username ON refresh_tokens(username);
+
+-- 创建令牌黑名单表（记录保留到令牌自然过期）
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(128) PRIMARY KEY,
+    username VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+-- 创建用户级吊销表（"登出所有会话"，签发时间早于revoked_before的令牌失效）
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+    username VARCHAR(100) PRIMARY KEY,
+    revoked_before TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
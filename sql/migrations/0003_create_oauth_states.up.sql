@@ -0,0 +1,9 @@
+-- 创建OAuth state表（auth.stateStore=pgsql时使用，支持多副本共享state）
+CREATE TABLE IF NOT EXISTS oauth_states (
+    state VARCHAR(128) PRIMARY KEY,
+    data TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states(expires_at);
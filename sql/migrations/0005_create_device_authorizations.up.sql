@@ -0,0 +1,17 @@
+-- 创建设备授权表（RFC 8628设备码登录，CLI使用）
+CREATE TABLE IF NOT EXISTS device_authorizations (
+    id SERIAL PRIMARY KEY,
+    device_code_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_code VARCHAR(16) UNIQUE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    username VARCHAR(100) DEFAULT '',
+    access_token TEXT DEFAULT '',
+    refresh_token TEXT DEFAULT '',
+    token_expires_at TIMESTAMP,
+    poll_interval INTEGER NOT NULL DEFAULT 5,
+    last_polled_at TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_authorizations_expires_at ON device_authorizations(expires_at);
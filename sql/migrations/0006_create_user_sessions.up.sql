@@ -0,0 +1,17 @@
+-- 创建用户会话表（每次登录成功记录一条，支持查看和结束会话）
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(100) NOT NULL,
+    token_id VARCHAR(128) UNIQUE NOT NULL,
+    family_id VARCHAR(64) DEFAULT '',
+    user_agent VARCHAR(500) DEFAULT '',
+    ip VARCHAR(64) DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    terminated_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_family_id ON user_sessions(family_id);
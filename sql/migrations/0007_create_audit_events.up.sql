@@ -0,0 +1,19 @@
+-- 创建审计事件表（登录、登出、令牌拒绝等安全相关事件）
+-- user_id不设外键，用户删除后仍保留其审计记录
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    user_id INTEGER NOT NULL DEFAULT 0,
+    username VARCHAR(100) DEFAULT '',
+    success BOOLEAN NOT NULL DEFAULT TRUE,
+    reason VARCHAR(500) DEFAULT '',
+    ip VARCHAR(64) DEFAULT '',
+    user_agent VARCHAR(500) DEFAULT '',
+    request_id VARCHAR(64) DEFAULT '',
+    detail JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_event_type ON audit_events(event_type, created_at);
@@ -0,0 +1,12 @@
+-- 创建Webhook事件表（按事件ID去重，防止重复投递被重复处理）
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(128) UNIQUE NOT NULL,
+    source VARCHAR(50) NOT NULL DEFAULT '',
+    action VARCHAR(100) DEFAULT '',
+    username VARCHAR(100) DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'processing',
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_received_at ON webhook_events(received_at);
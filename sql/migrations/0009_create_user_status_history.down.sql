@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_status_history;
+ALTER TABLE users DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_reason;
@@ -0,0 +1,17 @@
+-- 用户表记录最近一次状态变更的原因和时间
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_reason VARCHAR(500) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+-- 创建用户状态变更记录表
+CREATE TABLE IF NOT EXISTS user_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_status INTEGER NOT NULL,
+    to_status INTEGER NOT NULL,
+    reason VARCHAR(500) DEFAULT '',
+    source VARCHAR(50) NOT NULL DEFAULT '',
+    actor VARCHAR(100) DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_status_history_user_id ON user_status_history(user_id, created_at);
@@ -0,0 +1,20 @@
+-- 存在已删除的用户时拒绝回滚：删除这些行会级联删除其状态历史等数据，保留则可能与重新注册的用户违反唯一约束
+-- 需先确认并手动处理这些用户后再回滚
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'cannot revert 0010_soft_delete_users: soft-deleted users exist, handle them manually first';
+    END IF;
+END
+$$;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS uniq_users_casdoor_id;
+DROP INDEX IF EXISTS uniq_users_email;
+DROP INDEX IF EXISTS uniq_users_username;
+ALTER TABLE users ADD CONSTRAINT users_username_key UNIQUE (username);
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_casdoor_id_key UNIQUE (casdoor_id);
+
+ALTER TABLE users DROP COLUMN IF EXISTS anonymized_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
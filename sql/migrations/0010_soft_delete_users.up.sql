@@ -0,0 +1,14 @@
+-- 用户软删除：deleted_at为删除时间，anonymized_at为个人信息匿名化的时间
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+
+-- 唯一约束只约束未删除的用户，已删除用户的用户名、邮箱和Casdoor用户ID可以重新使用
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_casdoor_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_users_username ON users(username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_users_email ON users(email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_users_casdoor_id ON users(casdoor_id) WHERE deleted_at IS NULL;
+
+-- 匿名化任务按删除时间查找待处理的用户
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL AND anonymized_at IS NULL;
@@ -0,0 +1,16 @@
+-- 创建个人数据导出表（异步生成的导出包，下载链接到期后删除）
+CREATE TABLE IF NOT EXISTS data_exports (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    token_hash VARCHAR(64) NOT NULL,
+    archive BYTEA,
+    size BIGINT NOT NULL DEFAULT 0,
+    error VARCHAR(500) DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at);
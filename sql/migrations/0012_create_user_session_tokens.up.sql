@@ -0,0 +1,13 @@
+-- 创建会话令牌表：会话签发过的全部访问令牌（含刷新前的）都关联到会话，结束会话后一并失效
+CREATE TABLE IF NOT EXISTS user_session_tokens (
+    token_id VARCHAR(128) PRIMARY KEY,
+    session_id INTEGER NOT NULL REFERENCES user_sessions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_session_tokens_session_id ON user_session_tokens(session_id);
+
+-- 已有会话关联其当前访问令牌
+INSERT INTO user_session_tokens (token_id, session_id, created_at)
+SELECT token_id, id, created_at FROM user_sessions
+ON CONFLICT (token_id) DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE device_authorizations DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE device_authorizations DROP COLUMN IF EXISTS client_ip;
@@ -0,0 +1,3 @@
+-- 设备授权记录发起请求的客户端，浏览器确认授权时展示给用户
+ALTER TABLE device_authorizations ADD COLUMN IF NOT EXISTS client_ip VARCHAR(64) DEFAULT '';
+ALTER TABLE device_authorizations ADD COLUMN IF NOT EXISTS user_agent VARCHAR(500) DEFAULT '';
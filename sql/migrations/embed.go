@@ -0,0 +1,12 @@
+// Package migrations 数据库迁移脚本，编译进二进制
+//
+// 文件命名为 <版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql，版本号按数字递增，
+// 已发布的迁移不能修改，结构变更需新增迁移
+package migrations
+
+import "embed"
+
+// FS 全部迁移脚本
+//
+//go:embed *.sql
+var FS embed.FS